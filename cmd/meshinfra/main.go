@@ -0,0 +1,52 @@
+// Command meshinfra renders the charts of the service meshes to kubernetes
+// manifests with the same transformers Meshery uses, for scripts and GitOps
+// pipelines:
+//
+//	meshinfra meshes
+//	meshinfra render linkerd --chart linkerd2 --repo https://helm.linkerd.io/stable -n linkerd
+//
+// It serves the transformers to the Meshery adapters over gRPC as well:
+//
+//	meshinfra serve --grpc-address :50051 --http-address :8080
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// An interrupt cancels the transform, the network steps give up at once
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+
+	if err := newRootCmd(ctx, os.Stdout, os.Stderr).Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// newRootCmd returns the meshinfra command writing to out and errOut
+func newRootCmd(ctx context.Context, out, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "meshinfra",
+		Short:        "Transform the charts of the service meshes to kubernetes manifests",
+		SilenceUsage: true,
+	}
+	cmd.SetOut(out)
+	cmd.SetErr(errOut)
+
+	cmd.AddCommand(newMeshesCmd(), newRenderCmd(ctx), newServeCmd(ctx))
+	return cmd
+}
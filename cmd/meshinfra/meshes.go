@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/spf13/cobra"
+)
+
+func newMeshesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "meshes",
+		Short: "List the supported meshes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			for _, mesh := range transformer.Meshes() {
+				if _, err := fmt.Fprintln(cmd.OutOrStdout(), mesh); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Aisuko/meshinfra/pkg/log"
+	"github.com/Aisuko/meshinfra/pkg/meshes"
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/postrender"
+	"sigs.k8s.io/yaml"
+)
+
+// renderOptions are the flags of the render command
+type renderOptions struct {
+	req         transformer.Request
+	meshOptions string
+	// kustomization is the YAML file of the kustomization
+	kustomization string
+	postRenderers []string
+	// cosignKey is the PEM file of the cosign public key
+	cosignKey string
+	outputDir string
+	timeout   time.Duration
+	debug     bool
+}
+
+func newRenderCmd(ctx context.Context) *cobra.Command {
+	o := &renderOptions{}
+	cmd := &cobra.Command{
+		Use:   "render MESH",
+		Short: "Render the chart of a mesh to kubernetes manifests",
+		Long: `Render the chart of a mesh to kubernetes manifests, the manifests are written
+to stdout or to a file per chart template in the output directory.
+
+The chart is taken from a local directory or archive, or from a chart repo
+or an oci:// registry. Run "meshinfra meshes" for the supported meshes.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(ctx, args[0], cmd.OutOrStdout(), cmd.ErrOrStderr())
+		},
+	}
+
+	f := cmd.Flags()
+	req := &o.req
+	f.StringVar(&req.ChartName, "chart", "", "name of the chart in the chart repo")
+	f.StringVar(&req.ChartRepoAddress, "repo", "", "address of the chart repo, or oci:// address of the registry")
+	f.StringVar(&req.RepoName, "repo-name", "", "name of the chart repo in the repositories file, default is the mesh name")
+	f.StringVar(&req.ChartPath, "chart-path", "", "local chart directory or packaged chart, the chart repo is not used")
+	f.StringVar(&req.Version, "version", "", "version or semver range of the chart, default is the latest stable version")
+	f.BoolVar(&req.Devel, "devel", false, "include the prerelease versions of the chart")
+	f.StringVarP(&req.Namespace, "namespace", "n", "", "namespace the chart is rendered into")
+	f.StringVar(&req.ReleaseName, "release", "", "release name, default is the mesh name")
+	f.BoolVar(&req.IsHa, "ha", false, "render the HA profile of the mesh")
+	f.StringSliceVar(&req.Profiles, "profile", nil, "values files of the chart to apply, like ha for values-ha.yaml (can be repeated)")
+	f.StringSliceVarP(&req.Values.ValueFiles, "values", "f", nil, "values files or URLs (can be repeated)")
+	f.StringArrayVar(&req.Values.Set, "set", nil, "set values, key1=val1,key2=val2 (can be repeated)")
+	f.StringArrayVar(&req.Values.SetString, "set-string", nil, "set string values, key1=val1,key2=val2 (can be repeated)")
+	f.StringArrayVar(&req.Values.SetFile, "set-file", nil, "set values from files, key1=path1,key2=path2 (can be repeated)")
+	f.StringVar(&o.meshOptions, "mesh-options", "", "YAML file of the mesh specific options, like profile and revision of istio")
+	f.StringVar(&o.kustomization, "kustomization", "", "YAML file of the kustomization applied to the manifest, like namespace, commonLabels and patchesStrategicMerge")
+	f.StringArrayVar(&o.postRenderers, "post-renderer", nil, "binary the manifest is piped through after the kustomization (can be repeated)")
+
+	f.StringVar(&req.Repo.Username, "username", "", "username of the chart repo or the registry")
+	f.StringVar(&req.Repo.Password, "password", "", "password of the chart repo or the registry")
+	f.StringVar(&req.Repo.BearerToken, "bearer-token", "", "bearer token of the chart repo, it wins over the username and the password")
+	f.StringVar(&req.Repo.CAFile, "ca-file", "", "CA bundle the chart repo is verified with")
+	f.StringVar(&req.Repo.CertFile, "cert-file", "", "client certificate of the chart repo")
+	f.StringVar(&req.Repo.KeyFile, "key-file", "", "client key of the chart repo")
+	f.BoolVar(&req.Repo.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "skip the verification of the chart repo certificate")
+	f.BoolVar(&req.Registry.PlainHTTP, "plain-http", false, "talk to the registry over http")
+	f.StringVar(&req.Verify.Keyring, "keyring", "", "PGP keyring the provenance file of the chart is verified against")
+	f.StringVar(&o.cosignKey, "cosign-key", "", "PEM public key the cosign signatures of the charts of the registry are verified with")
+	f.BoolVar(&req.Verify.Required, "verify", false, "fail unless the chart is verified")
+
+	defaults := transformer.DefaultSettings()
+	f.StringVar(&req.Settings.RepositoryConfig, "repository-config", defaults.RepositoryConfig, "path of the repositories file")
+	f.StringVar(&req.Settings.RepositoryCache, "repository-cache", defaults.RepositoryCache, "path of the cache of the repo indexes and charts")
+	f.StringVar(&req.Settings.RegistryConfig, "registry-config", defaults.RegistryConfig, "path of the registry config file")
+
+	f.StringVarP(&o.outputDir, "output-dir", "o", "", "write the manifests to a file per chart template in the directory")
+	f.DurationVar(&o.timeout, "timeout", 5*time.Minute, "time to wait for the transform")
+	f.BoolVar(&o.debug, "debug", false, "log the steps of the transform to stderr")
+	return cmd
+}
+
+// run renders the chart of the mesh and writes the manifests
+func (o *renderOptions) run(ctx context.Context, mesh string, out, errOut io.Writer) error {
+	req := o.req
+	if req.ReleaseName == "" {
+		req.ReleaseName = mesh
+	}
+	if req.RepoName == "" {
+		req.RepoName = mesh
+	}
+	req.Registry.Username = req.Repo.Username
+	req.Registry.Password = req.Repo.Password
+	if o.debug {
+		l := logrus.New()
+		l.SetOutput(errOut)
+		l.SetLevel(logrus.DebugLevel)
+		req.Logger = log.NewLogrus(l)
+	}
+	if o.meshOptions != "" {
+		opts, err := readMeshOptions(mesh, o.meshOptions)
+		if err != nil {
+			return err
+		}
+		req.Options = opts
+	}
+	if o.cosignKey != "" {
+		key, err := ioutil.ReadFile(o.cosignKey)
+		if err != nil {
+			return err
+		}
+		req.Verify.CosignKey = key
+	}
+	if o.kustomization != "" {
+		k, err := readKustomization(o.kustomization)
+		if err != nil {
+			return err
+		}
+		req.PostRender.Kustomization = k
+	}
+	for _, path := range o.postRenderers {
+		r, err := postrender.NewExec(path)
+		if err != nil {
+			return err
+		}
+		req.PostRender.Renderers = append(req.PostRender.Renderers, r)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+	result, err := transformer.Transform(ctx, mesh, &req)
+	if err != nil {
+		return err
+	}
+
+	if o.outputDir == "" {
+		_, err := io.WriteString(out, result.Manifest)
+		return err
+	}
+	return writeObjects(o.outputDir, result.Objects)
+}
+
+// readMeshOptions decodes the mesh options file into the options of the mesh
+func readMeshOptions(mesh, path string) (interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := meshes.DecodeOptions(mesh, data)
+	return opts, errors.Wrapf(err, "failed reading %s", path)
+}
+
+// readKustomization decodes the kustomization file, the unknown fields are an
+// error
+func readKustomization(path string) (*transformer.Kustomization, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	k := &transformer.Kustomization{}
+	if err := yaml.UnmarshalStrict(data, k); err != nil {
+		return nil, errors.Wrapf(err, "failed parsing the kustomization %s", path)
+	}
+	return k, nil
+}
+
+// writeObjects writes the objects to a file per chart template in the
+// directory, like helm template --output-dir. A template path which is absolute
+// or leads out of the directory is an error, nothing is written then.
+func writeObjects(dir string, objects []transformer.Object) error {
+	var sources []string
+	files := map[string][]byte{}
+	for _, obj := range objects {
+		source := obj.Source
+		if source == "" {
+			source = "manifest.yaml"
+		}
+		if path := filepath.Clean(filepath.FromSlash(source)); filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+			return errors.Errorf("the template %s is outside the output directory", source)
+		}
+		if _, ok := files[source]; !ok {
+			sources = append(sources, source)
+		}
+		files[source] = append(files[source], []byte("---\n"+obj.Raw)...)
+	}
+
+	for _, source := range sources {
+		path := filepath.Join(dir, filepath.FromSlash(source))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, files[source], 0644); err != nil {
+			return errors.Wrapf(err, "failed writing %s", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/Aisuko/meshinfra/pkg/transformer/registrytest"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// execute runs the meshinfra command with the args and returns its output
+func execute(args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := newRootCmd(context.Background(), &out, ioutil.Discard)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestMeshes(t *testing.T) {
+	out, err := execute("meshes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "consul\nistio\nkuma\nlinkerd\nosm\ntraefik-mesh\n"; out != want {
+		t.Errorf("meshes printed %q, want %q", out, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	out, err := execute("render", "consul", "--chart-path", "../../pkg/consul/testdata/consul", "-n", "consul", "--set", "server.replicas=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"kind: StatefulSet", "namespace: consul", "replicas: 5"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("manifest does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderOutputDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	options := filepath.Join(dir, "istio.yaml")
+	if err := ioutil.WriteFile(options, []byte("profile: minimal\nrevision: canary\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifests := filepath.Join(dir, "manifests")
+	out, err := execute("render", "istio", "--chart-path", "../../pkg/istio/testdata/charts", "--mesh-options", options, "--output-dir", manifests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "" {
+		t.Errorf("render printed %q with an output directory", out)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(manifests, "istiod", "templates", "deployment.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "---\n") || !strings.Contains(string(data), "name: istiod-canary") {
+		t.Errorf("unexpected istiod deployment:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(manifests, "gateway")); !os.IsNotExist(err) {
+		t.Errorf("the gateway of the minimal profile is rendered: %v", err)
+	}
+}
+
+func TestWriteObjectsOutsideDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	manifests := filepath.Join(dir, "manifests")
+
+	for _, source := range []string{"../escape.yaml", "mesh/../../escape.yaml", "..", filepath.Join(dir, "escape.yaml")} {
+		objects := []transformer.Object{{Source: "mesh/templates/a.yaml", Raw: "kind: A\n"}, {Source: source, Raw: "kind: B\n"}}
+		if err := writeObjects(manifests, objects); err == nil {
+			t.Errorf("writeObjects of the template %s should fail", source)
+		}
+	}
+	if files, err := ioutil.ReadDir(dir); err != nil || len(files) != 0 {
+		t.Errorf("writeObjects wrote %v (%v)", files, err)
+	}
+
+	if err := writeObjects(manifests, []transformer.Object{{Source: "mesh/templates/../a.yaml", Raw: "kind: A\n"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(manifests, "mesh", "a.yaml")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRenderPostRender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kustomization := filepath.Join(dir, "kustomization.yaml")
+	if err := ioutil.WriteFile(kustomization, []byte("namespace: mesh\ncommonLabels:\n  team: mesh\nimages:\n- name: consul\n  newTag: 1.8.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "post-render.sh")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\nsed 's/dc1/dc2/'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := execute("render", "consul", "--chart-path", "../../pkg/consul/testdata/consul", "--kustomization", kustomization, "--post-renderer", script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"namespace: mesh", "team: mesh", "image: consul:1.8.0", "-datacenter=dc2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("manifest does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	reg := registrytest.NewWithAuth("mesh", "secret")
+	defer reg.Close()
+	ch, err := loader.Load("../../pkg/consul/testdata/consul")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Push("meshes", ch); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Sign("meshes", "consul", ch.Metadata.Version, key); err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := func(name string, key *ecdsa.PrivateKey) string {
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	cosignKey, otherKey := keyFile("cosign.pub", key), keyFile("other.pub", other)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "credentials", args: []string{"--username", "mesh", "--password", "secret"}},
+		{name: "cosign key", args: []string{"--username", "mesh", "--password", "secret", "--cosign-key", cosignKey, "--verify"}},
+		{name: "other cosign key", args: []string{"--username", "mesh", "--password", "secret", "--cosign-key", otherKey}, wantErr: true},
+		{name: "no credentials", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{
+				"render", "consul", "--chart", "consul", "--repo", reg.Address("meshes"), "--plain-http",
+				"--registry-config", filepath.Join(dir, "missing.json"),
+				"--repository-cache", filepath.Join(dir, tt.name),
+			}, tt.args...)
+			out, err := execute(args...)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("render should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, "kind: StatefulSet") {
+				t.Errorf("render printed the manifest:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestRenderBearerToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	charts := filepath.Join(dir, "charts")
+	if err := os.MkdirAll(charts, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := http.FileServer(http.Dir(charts))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	ch, err := loader.Load("../../pkg/consul/testdata/consul")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chartutil.Save(ch, charts); err != nil {
+		t.Fatal(err)
+	}
+	index, err := repo.IndexDirectory(charts, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.WriteFile(filepath.Join(charts, "index.yaml"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{"token", "wrong"} {
+		out, err := execute("render", "consul", "--chart", "consul", "--repo", srv.URL, "--bearer-token", token,
+			"--repository-config", filepath.Join(dir, token, "repositories.yaml"),
+			"--repository-cache", filepath.Join(dir, token, "cache"))
+		if token == "wrong" {
+			if err == nil {
+				t.Error("render with the wrong bearer token should fail")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "kind: StatefulSet") {
+			t.Errorf("render printed the manifest:\n%s", out)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no mesh", []string{"render"}},
+		{"unknown mesh", []string{"render", "unknown", "--chart-path", "../../pkg/consul/testdata/consul"}},
+		{"mesh without options", []string{"render", "consul", "--mesh-options", "render_test.go"}},
+		{"unknown mesh option", []string{"render", "istio", "--mesh-options", "../../pkg/istio/testdata/charts/base/Chart.yaml"}},
+		{"unknown kustomization field", []string{"render", "consul", "--chart-path", "../../pkg/consul/testdata/consul", "--kustomization", "../../pkg/consul/testdata/consul/Chart.yaml"}},
+		{"missing post-renderer", []string{"render", "consul", "--chart-path", "../../pkg/consul/testdata/consul", "--post-renderer", "./missing"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := execute(tt.args...); err == nil {
+				t.Fatal("render should fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Aisuko/meshinfra/pkg/api"
+	"github.com/Aisuko/meshinfra/pkg/log"
+	"github.com/Aisuko/meshinfra/pkg/server"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"sigs.k8s.io/yaml"
+)
+
+// serveOptions are the flags of the serve command
+type serveOptions struct {
+	srv         server.Options
+	tenantsFile string
+	grpcAddress string
+	httpAddress string
+	debug       bool
+}
+
+func newServeCmd(ctx context.Context) *cobra.Command {
+	o := &serveOptions{}
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the transformers to the Meshery adapters over gRPC",
+		Long: `Serve the transformers to the Meshery adapters over gRPC, and over HTTP/JSON
+with --http-address. Every adapter names its tenant in the requests, the chart
+repos and the registry config of a tenant are kept in its own directory of the
+root. With --tenants-file the adapters authenticate as their tenant, they send
+its token as a bearer token.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return o.run(ctx, cmd.ErrOrStderr())
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&o.grpcAddress, "grpc-address", ":50051", "address the gRPC API listens on")
+	f.StringVar(&o.httpAddress, "http-address", "", "address the HTTP/JSON API listens on, it is off by default")
+	f.StringVar(&o.srv.Root, "root", "", "directory of the chart repos and the caches of the tenants, default is the meshinfra cache directory")
+	f.StringVar(&o.tenantsFile, "tenants-file", "", "YAML file of the tokens of the tenants by name, the tenants are not authenticated without it")
+	f.DurationVar(&o.srv.IndexTTL, "index-ttl", 0, "how long a cached repo index is used before it is refreshed")
+	f.DurationVar(&o.srv.Timeout, "timeout", server.DefaultTimeout, "time to wait for a request")
+	f.BoolVar(&o.debug, "debug", false, "log the steps of the requests")
+	return cmd
+}
+
+// run serves the APIs until the context is done, the requests in flight are
+// finished first
+func (o *serveOptions) run(ctx context.Context, errOut io.Writer) error {
+	l := logrus.New()
+	l.SetOutput(errOut)
+	if o.debug {
+		l.SetLevel(logrus.DebugLevel)
+	}
+	o.srv.Logger = log.NewLogrus(l)
+	if o.tenantsFile != "" {
+		data, err := ioutil.ReadFile(o.tenantsFile)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(data, &o.srv.Tenants); err != nil {
+			return errors.Wrapf(err, "failed parsing the tenants file %s", o.tenantsFile)
+		}
+	} else {
+		l.Warn("The tenants are not authenticated, every client can use the repos and the registry config of every tenant")
+	}
+	srv := server.New(o.srv)
+
+	lis, err := net.Listen("tcp", o.grpcAddress)
+	if err != nil {
+		return err
+	}
+	g := grpc.NewServer()
+	api.RegisterMeshInfraServer(g, srv)
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- g.Serve(lis)
+	}()
+	l.Infof("Serving gRPC on %s", lis.Addr())
+
+	var h *http.Server
+	if o.httpAddress != "" {
+		h = &http.Server{Addr: o.httpAddress, Handler: srv.Handler()}
+		go func() {
+			if err := h.ListenAndServe(); err != http.ErrServerClosed {
+				errs <- err
+			}
+		}()
+		l.Infof("Serving HTTP on %s", o.httpAddress)
+	}
+
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if h != nil {
+		_ = h.Shutdown(shutdownCtx)
+	}
+	g.GracefulStop()
+	return err
+}
@@ -3,13 +3,20 @@ module github.com/Aisuko/meshinfra
 go 1.14
 
 require (
+	github.com/Masterminds/semver/v3 v3.0.3
 	github.com/gofrs/flock v0.7.1
 	github.com/golang/mock v1.2.0
+	github.com/golang/protobuf v1.3.2
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.5.0
+	github.com/spf13/cobra v0.0.5
+	google.golang.org/grpc v1.27.0
 	gopkg.in/check.v1 v1.0.0-20200227125254-8fa46927fb4f // indirect
 	gopkg.in/yaml.v2 v2.2.8
 	helm.sh/helm/v3 v3.1.2
+	k8s.io/cli-runtime v0.17.2
 	rsc.io/letsencrypt v0.0.3 // indirect
+	sigs.k8s.io/kustomize v2.0.3+incompatible
+	sigs.k8s.io/yaml v1.1.0
 )
@@ -0,0 +1,5 @@
+// Package api is the gRPC API of the meshinfra server, the Meshery adapters
+// render the charts of the meshes through it instead of vendoring meshinfra.
+package api
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. meshinfra.proto
@@ -0,0 +1,1471 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: meshinfra.proto
+
+package api
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// Chart locates the chart of a mesh
+type Chart struct {
+	// name is the name of the chart in the chart repo
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// repo_address is the URL of the chart repo, or an oci:// address of the
+	// registry the chart is pulled from
+	RepoAddress string `protobuf:"bytes,2,opt,name=repo_address,json=repoAddress,proto3" json:"repo_address,omitempty"`
+	// repo_name is the name of the chart repo, default is the mesh name
+	RepoName string `protobuf:"bytes,3,opt,name=repo_name,json=repoName,proto3" json:"repo_name,omitempty"`
+	// version is the exact version or the semver range of the chart, default is
+	// the latest stable version
+	Version string `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"`
+	// devel includes the prerelease versions of the chart
+	Devel bool `protobuf:"varint,5,opt,name=devel,proto3" json:"devel,omitempty"`
+	// archive is a packaged .tgz chart, the chart repo is not used when it is set
+	Archive []byte `protobuf:"bytes,6,opt,name=archive,proto3" json:"archive,omitempty"`
+	// credentials are the credentials and the TLS settings of the chart repo or
+	// the registry
+	Credentials *Credentials `protobuf:"bytes,7,opt,name=credentials,proto3" json:"credentials,omitempty"`
+	// cosign_key is the PEM public key the cosign signature of a chart of a
+	// registry is verified with
+	CosignKey []byte `protobuf:"bytes,8,opt,name=cosign_key,json=cosignKey,proto3" json:"cosign_key,omitempty"`
+	// verify_required fails the request of a chart which has no signature
+	VerifyRequired       bool     `protobuf:"varint,9,opt,name=verify_required,json=verifyRequired,proto3" json:"verify_required,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Chart) Reset()         { *m = Chart{} }
+func (m *Chart) String() string { return proto.CompactTextString(m) }
+func (*Chart) ProtoMessage()    {}
+func (*Chart) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{0}
+}
+
+func (m *Chart) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Chart.Unmarshal(m, b)
+}
+func (m *Chart) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Chart.Marshal(b, m, deterministic)
+}
+func (m *Chart) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Chart.Merge(m, src)
+}
+func (m *Chart) XXX_Size() int {
+	return xxx_messageInfo_Chart.Size(m)
+}
+func (m *Chart) XXX_DiscardUnknown() {
+	xxx_messageInfo_Chart.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Chart proto.InternalMessageInfo
+
+func (m *Chart) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Chart) GetRepoAddress() string {
+	if m != nil {
+		return m.RepoAddress
+	}
+	return ""
+}
+
+func (m *Chart) GetRepoName() string {
+	if m != nil {
+		return m.RepoName
+	}
+	return ""
+}
+
+func (m *Chart) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *Chart) GetDevel() bool {
+	if m != nil {
+		return m.Devel
+	}
+	return false
+}
+
+func (m *Chart) GetArchive() []byte {
+	if m != nil {
+		return m.Archive
+	}
+	return nil
+}
+
+func (m *Chart) GetCredentials() *Credentials {
+	if m != nil {
+		return m.Credentials
+	}
+	return nil
+}
+
+func (m *Chart) GetCosignKey() []byte {
+	if m != nil {
+		return m.CosignKey
+	}
+	return nil
+}
+
+func (m *Chart) GetVerifyRequired() bool {
+	if m != nil {
+		return m.VerifyRequired
+	}
+	return false
+}
+
+// Credentials are the credentials and the TLS settings of a chart repo or a
+// registry, they are never stored by the server
+type Credentials struct {
+	Username string `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Password string `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	// bearer_token wins over the username and the password of a chart repo
+	BearerToken string `protobuf:"bytes,3,opt,name=bearer_token,json=bearerToken,proto3" json:"bearer_token,omitempty"`
+	// pass_credentials_all sends the credentials to the chart URLs on other
+	// hosts than the chart repo
+	PassCredentialsAll bool `protobuf:"varint,4,opt,name=pass_credentials_all,json=passCredentialsAll,proto3" json:"pass_credentials_all,omitempty"`
+	// ca_data is the PEM bundle of the CAs the chart repo is verified with
+	CaData []byte `protobuf:"bytes,5,opt,name=ca_data,json=caData,proto3" json:"ca_data,omitempty"`
+	// cert_data and key_data are the PEM client certificate and key
+	CertData              []byte `protobuf:"bytes,6,opt,name=cert_data,json=certData,proto3" json:"cert_data,omitempty"`
+	KeyData               []byte `protobuf:"bytes,7,opt,name=key_data,json=keyData,proto3" json:"key_data,omitempty"`
+	InsecureSkipTlsVerify bool   `protobuf:"varint,8,opt,name=insecure_skip_tls_verify,json=insecureSkipTlsVerify,proto3" json:"insecure_skip_tls_verify,omitempty"`
+	// plain_http talks to the registry over http
+	PlainHttp            bool     `protobuf:"varint,9,opt,name=plain_http,json=plainHttp,proto3" json:"plain_http,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Credentials) Reset()         { *m = Credentials{} }
+func (m *Credentials) String() string { return proto.CompactTextString(m) }
+func (*Credentials) ProtoMessage()    {}
+func (*Credentials) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{1}
+}
+
+func (m *Credentials) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Credentials.Unmarshal(m, b)
+}
+func (m *Credentials) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Credentials.Marshal(b, m, deterministic)
+}
+func (m *Credentials) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Credentials.Merge(m, src)
+}
+func (m *Credentials) XXX_Size() int {
+	return xxx_messageInfo_Credentials.Size(m)
+}
+func (m *Credentials) XXX_DiscardUnknown() {
+	xxx_messageInfo_Credentials.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Credentials proto.InternalMessageInfo
+
+func (m *Credentials) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *Credentials) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+func (m *Credentials) GetBearerToken() string {
+	if m != nil {
+		return m.BearerToken
+	}
+	return ""
+}
+
+func (m *Credentials) GetPassCredentialsAll() bool {
+	if m != nil {
+		return m.PassCredentialsAll
+	}
+	return false
+}
+
+func (m *Credentials) GetCaData() []byte {
+	if m != nil {
+		return m.CaData
+	}
+	return nil
+}
+
+func (m *Credentials) GetCertData() []byte {
+	if m != nil {
+		return m.CertData
+	}
+	return nil
+}
+
+func (m *Credentials) GetKeyData() []byte {
+	if m != nil {
+		return m.KeyData
+	}
+	return nil
+}
+
+func (m *Credentials) GetInsecureSkipTlsVerify() bool {
+	if m != nil {
+		return m.InsecureSkipTlsVerify
+	}
+	return false
+}
+
+func (m *Credentials) GetPlainHttp() bool {
+	if m != nil {
+		return m.PlainHttp
+	}
+	return false
+}
+
+// Values are the value overrides of the chart, they are applied in the order
+// of the fields like by helm install
+type Values struct {
+	// yaml are the values of a values file
+	Yaml string `protobuf:"bytes,1,opt,name=yaml,proto3" json:"yaml,omitempty"`
+	// set are the key=value overrides (--set)
+	Set []string `protobuf:"bytes,2,rep,name=set,proto3" json:"set,omitempty"`
+	// set_string are the key=value overrides whose values are always strings
+	// (--set-string)
+	SetString []string `protobuf:"bytes,3,rep,name=set_string,json=setString,proto3" json:"set_string,omitempty"`
+	// set_file are the values of the keys held as they are (--set-file)
+	SetFile              map[string][]byte `protobuf:"bytes,4,rep,name=set_file,json=setFile,proto3" json:"set_file,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Values) Reset()         { *m = Values{} }
+func (m *Values) String() string { return proto.CompactTextString(m) }
+func (*Values) ProtoMessage()    {}
+func (*Values) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{2}
+}
+
+func (m *Values) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Values.Unmarshal(m, b)
+}
+func (m *Values) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Values.Marshal(b, m, deterministic)
+}
+func (m *Values) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Values.Merge(m, src)
+}
+func (m *Values) XXX_Size() int {
+	return xxx_messageInfo_Values.Size(m)
+}
+func (m *Values) XXX_DiscardUnknown() {
+	xxx_messageInfo_Values.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Values proto.InternalMessageInfo
+
+func (m *Values) GetYaml() string {
+	if m != nil {
+		return m.Yaml
+	}
+	return ""
+}
+
+func (m *Values) GetSet() []string {
+	if m != nil {
+		return m.Set
+	}
+	return nil
+}
+
+func (m *Values) GetSetString() []string {
+	if m != nil {
+		return m.SetString
+	}
+	return nil
+}
+
+func (m *Values) GetSetFile() map[string][]byte {
+	if m != nil {
+		return m.SetFile
+	}
+	return nil
+}
+
+// Kustomization is the kustomize overlay of the manifest, the server runs no
+// post-renderer binaries
+type Kustomization struct {
+	// namespace rewrites the namespace of the namespaced objects
+	Namespace string `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// common_labels are added to the objects and to their selectors
+	CommonLabels      map[string]string `protobuf:"bytes,2,rep,name=common_labels,json=commonLabels,proto3" json:"common_labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	CommonAnnotations map[string]string `protobuf:"bytes,3,rep,name=common_annotations,json=commonAnnotations,proto3" json:"common_annotations,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Images            []*Image          `protobuf:"bytes,4,rep,name=images,proto3" json:"images,omitempty"`
+	// patches_strategic_merge are the strategic merge patches in YAML, each one
+	// names the object it patches
+	PatchesStrategicMerge []string     `protobuf:"bytes,5,rep,name=patches_strategic_merge,json=patchesStrategicMerge,proto3" json:"patches_strategic_merge,omitempty"`
+	PatchesJson6902       []*JsonPatch `protobuf:"bytes,6,rep,name=patches_json6902,json=patchesJson6902,proto3" json:"patches_json6902,omitempty"`
+	XXX_NoUnkeyedLiteral  struct{}     `json:"-"`
+	XXX_unrecognized      []byte       `json:"-"`
+	XXX_sizecache         int32        `json:"-"`
+}
+
+func (m *Kustomization) Reset()         { *m = Kustomization{} }
+func (m *Kustomization) String() string { return proto.CompactTextString(m) }
+func (*Kustomization) ProtoMessage()    {}
+func (*Kustomization) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{3}
+}
+
+func (m *Kustomization) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Kustomization.Unmarshal(m, b)
+}
+func (m *Kustomization) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Kustomization.Marshal(b, m, deterministic)
+}
+func (m *Kustomization) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Kustomization.Merge(m, src)
+}
+func (m *Kustomization) XXX_Size() int {
+	return xxx_messageInfo_Kustomization.Size(m)
+}
+func (m *Kustomization) XXX_DiscardUnknown() {
+	xxx_messageInfo_Kustomization.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Kustomization proto.InternalMessageInfo
+
+func (m *Kustomization) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *Kustomization) GetCommonLabels() map[string]string {
+	if m != nil {
+		return m.CommonLabels
+	}
+	return nil
+}
+
+func (m *Kustomization) GetCommonAnnotations() map[string]string {
+	if m != nil {
+		return m.CommonAnnotations
+	}
+	return nil
+}
+
+func (m *Kustomization) GetImages() []*Image {
+	if m != nil {
+		return m.Images
+	}
+	return nil
+}
+
+func (m *Kustomization) GetPatchesStrategicMerge() []string {
+	if m != nil {
+		return m.PatchesStrategicMerge
+	}
+	return nil
+}
+
+func (m *Kustomization) GetPatchesJson6902() []*JsonPatch {
+	if m != nil {
+		return m.PatchesJson6902
+	}
+	return nil
+}
+
+// Image is the override of an image, the name is the image name without a tag
+type Image struct {
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	NewName string `protobuf:"bytes,2,opt,name=new_name,json=newName,proto3" json:"new_name,omitempty"`
+	NewTag  string `protobuf:"bytes,3,opt,name=new_tag,json=newTag,proto3" json:"new_tag,omitempty"`
+	// digest wins over the new tag
+	Digest               string   `protobuf:"bytes,4,opt,name=digest,proto3" json:"digest,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Image) Reset()         { *m = Image{} }
+func (m *Image) String() string { return proto.CompactTextString(m) }
+func (*Image) ProtoMessage()    {}
+func (*Image) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{4}
+}
+
+func (m *Image) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Image.Unmarshal(m, b)
+}
+func (m *Image) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Image.Marshal(b, m, deterministic)
+}
+func (m *Image) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Image.Merge(m, src)
+}
+func (m *Image) XXX_Size() int {
+	return xxx_messageInfo_Image.Size(m)
+}
+func (m *Image) XXX_DiscardUnknown() {
+	xxx_messageInfo_Image.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Image proto.InternalMessageInfo
+
+func (m *Image) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Image) GetNewName() string {
+	if m != nil {
+		return m.NewName
+	}
+	return ""
+}
+
+func (m *Image) GetNewTag() string {
+	if m != nil {
+		return m.NewTag
+	}
+	return ""
+}
+
+func (m *Image) GetDigest() string {
+	if m != nil {
+		return m.Digest
+	}
+	return ""
+}
+
+// JsonPatch is a JSON patch, RFC 6902, of the object of the target
+type JsonPatch struct {
+	Target *PatchTarget `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	// patch are the operations in JSON or YAML
+	Patch                string   `protobuf:"bytes,2,opt,name=patch,proto3" json:"patch,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *JsonPatch) Reset()         { *m = JsonPatch{} }
+func (m *JsonPatch) String() string { return proto.CompactTextString(m) }
+func (*JsonPatch) ProtoMessage()    {}
+func (*JsonPatch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{5}
+}
+
+func (m *JsonPatch) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_JsonPatch.Unmarshal(m, b)
+}
+func (m *JsonPatch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_JsonPatch.Marshal(b, m, deterministic)
+}
+func (m *JsonPatch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_JsonPatch.Merge(m, src)
+}
+func (m *JsonPatch) XXX_Size() int {
+	return xxx_messageInfo_JsonPatch.Size(m)
+}
+func (m *JsonPatch) XXX_DiscardUnknown() {
+	xxx_messageInfo_JsonPatch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_JsonPatch proto.InternalMessageInfo
+
+func (m *JsonPatch) GetTarget() *PatchTarget {
+	if m != nil {
+		return m.Target
+	}
+	return nil
+}
+
+func (m *JsonPatch) GetPatch() string {
+	if m != nil {
+		return m.Patch
+	}
+	return ""
+}
+
+// PatchTarget names the object of a patch by its name and namespace in the
+// rendered manifest
+type PatchTarget struct {
+	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Version              string   `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Kind                 string   `protobuf:"bytes,3,opt,name=kind,proto3" json:"kind,omitempty"`
+	Namespace            string   `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Name                 string   `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PatchTarget) Reset()         { *m = PatchTarget{} }
+func (m *PatchTarget) String() string { return proto.CompactTextString(m) }
+func (*PatchTarget) ProtoMessage()    {}
+func (*PatchTarget) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{6}
+}
+
+func (m *PatchTarget) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PatchTarget.Unmarshal(m, b)
+}
+func (m *PatchTarget) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PatchTarget.Marshal(b, m, deterministic)
+}
+func (m *PatchTarget) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PatchTarget.Merge(m, src)
+}
+func (m *PatchTarget) XXX_Size() int {
+	return xxx_messageInfo_PatchTarget.Size(m)
+}
+func (m *PatchTarget) XXX_DiscardUnknown() {
+	xxx_messageInfo_PatchTarget.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PatchTarget proto.InternalMessageInfo
+
+func (m *PatchTarget) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *PatchTarget) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *PatchTarget) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
+func (m *PatchTarget) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *PatchTarget) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type RenderRequest struct {
+	// tenant is the name of the tenant of the request, like the name of the
+	// adapter, default is "default". The request authenticates as the tenant
+	// when the server has the tokens of the tenants.
+	Tenant string `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Mesh   string `protobuf:"bytes,2,opt,name=mesh,proto3" json:"mesh,omitempty"`
+	Chart  *Chart `protobuf:"bytes,3,opt,name=chart,proto3" json:"chart,omitempty"`
+	// release_name is the release name, default is the mesh name
+	ReleaseName string `protobuf:"bytes,4,opt,name=release_name,json=releaseName,proto3" json:"release_name,omitempty"`
+	// namespace is the namespace the chart is rendered into
+	Namespace string `protobuf:"bytes,5,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Ha        bool   `protobuf:"varint,6,opt,name=ha,proto3" json:"ha,omitempty"`
+	// profiles select the values files shipped in the chart, like ha for
+	// values-ha.yaml
+	Profiles []string `protobuf:"bytes,7,rep,name=profiles,proto3" json:"profiles,omitempty"`
+	Values   *Values  `protobuf:"bytes,8,opt,name=values,proto3" json:"values,omitempty"`
+	// options are the mesh specific options in YAML, like the profile of istio
+	Options string `protobuf:"bytes,9,opt,name=options,proto3" json:"options,omitempty"`
+	// kustomization is applied to the manifest after it is rendered
+	Kustomization        *Kustomization `protobuf:"bytes,10,opt,name=kustomization,proto3" json:"kustomization,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *RenderRequest) Reset()         { *m = RenderRequest{} }
+func (m *RenderRequest) String() string { return proto.CompactTextString(m) }
+func (*RenderRequest) ProtoMessage()    {}
+func (*RenderRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{7}
+}
+
+func (m *RenderRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenderRequest.Unmarshal(m, b)
+}
+func (m *RenderRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenderRequest.Marshal(b, m, deterministic)
+}
+func (m *RenderRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenderRequest.Merge(m, src)
+}
+func (m *RenderRequest) XXX_Size() int {
+	return xxx_messageInfo_RenderRequest.Size(m)
+}
+func (m *RenderRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenderRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenderRequest proto.InternalMessageInfo
+
+func (m *RenderRequest) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+func (m *RenderRequest) GetMesh() string {
+	if m != nil {
+		return m.Mesh
+	}
+	return ""
+}
+
+func (m *RenderRequest) GetChart() *Chart {
+	if m != nil {
+		return m.Chart
+	}
+	return nil
+}
+
+func (m *RenderRequest) GetReleaseName() string {
+	if m != nil {
+		return m.ReleaseName
+	}
+	return ""
+}
+
+func (m *RenderRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *RenderRequest) GetHa() bool {
+	if m != nil {
+		return m.Ha
+	}
+	return false
+}
+
+func (m *RenderRequest) GetProfiles() []string {
+	if m != nil {
+		return m.Profiles
+	}
+	return nil
+}
+
+func (m *RenderRequest) GetValues() *Values {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+func (m *RenderRequest) GetOptions() string {
+	if m != nil {
+		return m.Options
+	}
+	return ""
+}
+
+func (m *RenderRequest) GetKustomization() *Kustomization {
+	if m != nil {
+		return m.Kustomization
+	}
+	return nil
+}
+
+// Object is a single kubernetes object of the manifest
+type Object struct {
+	ApiVersion string `protobuf:"bytes,1,opt,name=api_version,json=apiVersion,proto3" json:"api_version,omitempty"`
+	Kind       string `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	Namespace  string `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Name       string `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	// source is the path of the chart template the object was rendered from
+	Source string `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"`
+	// raw is the YAML of the object
+	Raw                  string   `protobuf:"bytes,6,opt,name=raw,proto3" json:"raw,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Object) Reset()         { *m = Object{} }
+func (m *Object) String() string { return proto.CompactTextString(m) }
+func (*Object) ProtoMessage()    {}
+func (*Object) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{8}
+}
+
+func (m *Object) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Object.Unmarshal(m, b)
+}
+func (m *Object) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Object.Marshal(b, m, deterministic)
+}
+func (m *Object) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Object.Merge(m, src)
+}
+func (m *Object) XXX_Size() int {
+	return xxx_messageInfo_Object.Size(m)
+}
+func (m *Object) XXX_DiscardUnknown() {
+	xxx_messageInfo_Object.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Object proto.InternalMessageInfo
+
+func (m *Object) GetApiVersion() string {
+	if m != nil {
+		return m.ApiVersion
+	}
+	return ""
+}
+
+func (m *Object) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
+func (m *Object) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *Object) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Object) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *Object) GetRaw() string {
+	if m != nil {
+		return m.Raw
+	}
+	return ""
+}
+
+// Dependency is the resolved version of a dependency of the chart
+type Dependency struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version              string   `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Repository           string   `protobuf:"bytes,3,opt,name=repository,proto3" json:"repository,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Dependency) Reset()         { *m = Dependency{} }
+func (m *Dependency) String() string { return proto.CompactTextString(m) }
+func (*Dependency) ProtoMessage()    {}
+func (*Dependency) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{9}
+}
+
+func (m *Dependency) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Dependency.Unmarshal(m, b)
+}
+func (m *Dependency) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Dependency.Marshal(b, m, deterministic)
+}
+func (m *Dependency) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Dependency.Merge(m, src)
+}
+func (m *Dependency) XXX_Size() int {
+	return xxx_messageInfo_Dependency.Size(m)
+}
+func (m *Dependency) XXX_DiscardUnknown() {
+	xxx_messageInfo_Dependency.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Dependency proto.InternalMessageInfo
+
+func (m *Dependency) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Dependency) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *Dependency) GetRepository() string {
+	if m != nil {
+		return m.Repository
+	}
+	return ""
+}
+
+type RenderResponse struct {
+	Manifest string    `protobuf:"bytes,1,opt,name=manifest,proto3" json:"manifest,omitempty"`
+	Objects  []*Object `protobuf:"bytes,2,rep,name=objects,proto3" json:"objects,omitempty"`
+	// version is the version of the rendered chart
+	Version string `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	// profiles are the chart values files applied by the profiles
+	Profiles     []string      `protobuf:"bytes,4,rep,name=profiles,proto3" json:"profiles,omitempty"`
+	Dependencies []*Dependency `protobuf:"bytes,5,rep,name=dependencies,proto3" json:"dependencies,omitempty"`
+	// output is the mesh specific output in YAML, like the identity
+	// certificates generated for linkerd
+	Output               string   `protobuf:"bytes,6,opt,name=output,proto3" json:"output,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RenderResponse) Reset()         { *m = RenderResponse{} }
+func (m *RenderResponse) String() string { return proto.CompactTextString(m) }
+func (*RenderResponse) ProtoMessage()    {}
+func (*RenderResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{10}
+}
+
+func (m *RenderResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenderResponse.Unmarshal(m, b)
+}
+func (m *RenderResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenderResponse.Marshal(b, m, deterministic)
+}
+func (m *RenderResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenderResponse.Merge(m, src)
+}
+func (m *RenderResponse) XXX_Size() int {
+	return xxx_messageInfo_RenderResponse.Size(m)
+}
+func (m *RenderResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenderResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenderResponse proto.InternalMessageInfo
+
+func (m *RenderResponse) GetManifest() string {
+	if m != nil {
+		return m.Manifest
+	}
+	return ""
+}
+
+func (m *RenderResponse) GetObjects() []*Object {
+	if m != nil {
+		return m.Objects
+	}
+	return nil
+}
+
+func (m *RenderResponse) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *RenderResponse) GetProfiles() []string {
+	if m != nil {
+		return m.Profiles
+	}
+	return nil
+}
+
+func (m *RenderResponse) GetDependencies() []*Dependency {
+	if m != nil {
+		return m.Dependencies
+	}
+	return nil
+}
+
+func (m *RenderResponse) GetOutput() string {
+	if m != nil {
+		return m.Output
+	}
+	return ""
+}
+
+type ListMeshesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListMeshesRequest) Reset()         { *m = ListMeshesRequest{} }
+func (m *ListMeshesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListMeshesRequest) ProtoMessage()    {}
+func (*ListMeshesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{11}
+}
+
+func (m *ListMeshesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListMeshesRequest.Unmarshal(m, b)
+}
+func (m *ListMeshesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListMeshesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListMeshesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListMeshesRequest.Merge(m, src)
+}
+func (m *ListMeshesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListMeshesRequest.Size(m)
+}
+func (m *ListMeshesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListMeshesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListMeshesRequest proto.InternalMessageInfo
+
+type ListMeshesResponse struct {
+	Meshes               []string `protobuf:"bytes,1,rep,name=meshes,proto3" json:"meshes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListMeshesResponse) Reset()         { *m = ListMeshesResponse{} }
+func (m *ListMeshesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListMeshesResponse) ProtoMessage()    {}
+func (*ListMeshesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{12}
+}
+
+func (m *ListMeshesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListMeshesResponse.Unmarshal(m, b)
+}
+func (m *ListMeshesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListMeshesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListMeshesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListMeshesResponse.Merge(m, src)
+}
+func (m *ListMeshesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListMeshesResponse.Size(m)
+}
+func (m *ListMeshesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListMeshesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListMeshesResponse proto.InternalMessageInfo
+
+func (m *ListMeshesResponse) GetMeshes() []string {
+	if m != nil {
+		return m.Meshes
+	}
+	return nil
+}
+
+type ListChartVersionsRequest struct {
+	Tenant string `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	// mesh names the chart repo when the chart has no repo name
+	Mesh                 string   `protobuf:"bytes,2,opt,name=mesh,proto3" json:"mesh,omitempty"`
+	Chart                *Chart   `protobuf:"bytes,3,opt,name=chart,proto3" json:"chart,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListChartVersionsRequest) Reset()         { *m = ListChartVersionsRequest{} }
+func (m *ListChartVersionsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListChartVersionsRequest) ProtoMessage()    {}
+func (*ListChartVersionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{13}
+}
+
+func (m *ListChartVersionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListChartVersionsRequest.Unmarshal(m, b)
+}
+func (m *ListChartVersionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListChartVersionsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListChartVersionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListChartVersionsRequest.Merge(m, src)
+}
+func (m *ListChartVersionsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListChartVersionsRequest.Size(m)
+}
+func (m *ListChartVersionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListChartVersionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListChartVersionsRequest proto.InternalMessageInfo
+
+func (m *ListChartVersionsRequest) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+func (m *ListChartVersionsRequest) GetMesh() string {
+	if m != nil {
+		return m.Mesh
+	}
+	return ""
+}
+
+func (m *ListChartVersionsRequest) GetChart() *Chart {
+	if m != nil {
+		return m.Chart
+	}
+	return nil
+}
+
+type ListChartVersionsResponse struct {
+	// versions are the versions which match the version of the chart, from the
+	// latest on
+	Versions             []string `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListChartVersionsResponse) Reset()         { *m = ListChartVersionsResponse{} }
+func (m *ListChartVersionsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListChartVersionsResponse) ProtoMessage()    {}
+func (*ListChartVersionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{14}
+}
+
+func (m *ListChartVersionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListChartVersionsResponse.Unmarshal(m, b)
+}
+func (m *ListChartVersionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListChartVersionsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListChartVersionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListChartVersionsResponse.Merge(m, src)
+}
+func (m *ListChartVersionsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListChartVersionsResponse.Size(m)
+}
+func (m *ListChartVersionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListChartVersionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListChartVersionsResponse proto.InternalMessageInfo
+
+func (m *ListChartVersionsResponse) GetVersions() []string {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+type ValidateResponse struct {
+	Valid bool `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	// error tells why the request is not valid
+	Error string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	// objects are the objects the chart renders, without their YAML
+	Objects              []*Object `protobuf:"bytes,3,rep,name=objects,proto3" json:"objects,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *ValidateResponse) Reset()         { *m = ValidateResponse{} }
+func (m *ValidateResponse) String() string { return proto.CompactTextString(m) }
+func (*ValidateResponse) ProtoMessage()    {}
+func (*ValidateResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bfc70d41f2ce15b1, []int{15}
+}
+
+func (m *ValidateResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValidateResponse.Unmarshal(m, b)
+}
+func (m *ValidateResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValidateResponse.Marshal(b, m, deterministic)
+}
+func (m *ValidateResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValidateResponse.Merge(m, src)
+}
+func (m *ValidateResponse) XXX_Size() int {
+	return xxx_messageInfo_ValidateResponse.Size(m)
+}
+func (m *ValidateResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValidateResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValidateResponse proto.InternalMessageInfo
+
+func (m *ValidateResponse) GetValid() bool {
+	if m != nil {
+		return m.Valid
+	}
+	return false
+}
+
+func (m *ValidateResponse) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *ValidateResponse) GetObjects() []*Object {
+	if m != nil {
+		return m.Objects
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Chart)(nil), "meshinfra.v1.Chart")
+	proto.RegisterType((*Credentials)(nil), "meshinfra.v1.Credentials")
+	proto.RegisterType((*Values)(nil), "meshinfra.v1.Values")
+	proto.RegisterMapType((map[string][]byte)(nil), "meshinfra.v1.Values.SetFileEntry")
+	proto.RegisterType((*Kustomization)(nil), "meshinfra.v1.Kustomization")
+	proto.RegisterMapType((map[string]string)(nil), "meshinfra.v1.Kustomization.CommonAnnotationsEntry")
+	proto.RegisterMapType((map[string]string)(nil), "meshinfra.v1.Kustomization.CommonLabelsEntry")
+	proto.RegisterType((*Image)(nil), "meshinfra.v1.Image")
+	proto.RegisterType((*JsonPatch)(nil), "meshinfra.v1.JsonPatch")
+	proto.RegisterType((*PatchTarget)(nil), "meshinfra.v1.PatchTarget")
+	proto.RegisterType((*RenderRequest)(nil), "meshinfra.v1.RenderRequest")
+	proto.RegisterType((*Object)(nil), "meshinfra.v1.Object")
+	proto.RegisterType((*Dependency)(nil), "meshinfra.v1.Dependency")
+	proto.RegisterType((*RenderResponse)(nil), "meshinfra.v1.RenderResponse")
+	proto.RegisterType((*ListMeshesRequest)(nil), "meshinfra.v1.ListMeshesRequest")
+	proto.RegisterType((*ListMeshesResponse)(nil), "meshinfra.v1.ListMeshesResponse")
+	proto.RegisterType((*ListChartVersionsRequest)(nil), "meshinfra.v1.ListChartVersionsRequest")
+	proto.RegisterType((*ListChartVersionsResponse)(nil), "meshinfra.v1.ListChartVersionsResponse")
+	proto.RegisterType((*ValidateResponse)(nil), "meshinfra.v1.ValidateResponse")
+}
+
+func init() { proto.RegisterFile("meshinfra.proto", fileDescriptor_bfc70d41f2ce15b1) }
+
+var fileDescriptor_bfc70d41f2ce15b1 = []byte{
+	// 1325 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x57, 0xcd, 0x72, 0x13, 0x47,
+	0x10, 0x2e, 0xfd, 0xad, 0xa5, 0x96, 0x0c, 0x78, 0x00, 0x7b, 0x6d, 0x08, 0x88, 0x3d, 0x04, 0x53,
+	0x21, 0x32, 0x28, 0x55, 0x90, 0x00, 0x55, 0x29, 0x63, 0xf2, 0xc3, 0x5f, 0x48, 0xad, 0x5d, 0x3e,
+	0x70, 0xd9, 0x1a, 0xef, 0xb6, 0xa5, 0x41, 0xab, 0xdd, 0x65, 0x66, 0x64, 0x97, 0x72, 0xcd, 0x2b,
+	0xe4, 0x92, 0x57, 0xc8, 0x6b, 0xe4, 0x90, 0x6b, 0x9e, 0x20, 0xe7, 0x3c, 0x46, 0x6a, 0x7e, 0x56,
+	0xda, 0x95, 0x65, 0x48, 0x0e, 0xb9, 0x4d, 0x77, 0x7f, 0xdd, 0xd3, 0xf3, 0xf5, 0xcf, 0x4a, 0x70,
+	0x71, 0x8c, 0x62, 0xc8, 0x92, 0x63, 0x4e, 0x7b, 0x19, 0x4f, 0x65, 0x4a, 0x3a, 0x73, 0xc5, 0xc9,
+	0x7d, 0xef, 0xb7, 0x2a, 0x34, 0xf6, 0x86, 0x94, 0x4b, 0x42, 0xa0, 0x9e, 0xd0, 0x31, 0xba, 0x95,
+	0x6e, 0x65, 0xbb, 0xe5, 0xeb, 0x33, 0xb9, 0x05, 0x1d, 0x8e, 0x59, 0x1a, 0xd0, 0x28, 0xe2, 0x28,
+	0x84, 0x5b, 0xd5, 0xb6, 0xb6, 0xd2, 0xed, 0x1a, 0x15, 0xb9, 0x06, 0x2d, 0x0d, 0xd1, 0xbe, 0x35,
+	0x6d, 0x6f, 0x2a, 0xc5, 0x0f, 0xca, 0xdf, 0x85, 0x95, 0x13, 0xe4, 0x82, 0xa5, 0x89, 0x5b, 0xd7,
+	0xa6, 0x5c, 0x24, 0x57, 0xa0, 0x11, 0xe1, 0x09, 0xc6, 0x6e, 0xa3, 0x5b, 0xd9, 0x6e, 0xfa, 0x46,
+	0x50, 0x78, 0xca, 0xc3, 0x21, 0x3b, 0x41, 0xd7, 0xe9, 0x56, 0xb6, 0x3b, 0x7e, 0x2e, 0x92, 0xc7,
+	0xd0, 0x0e, 0x39, 0x46, 0x98, 0x48, 0x46, 0x63, 0xe1, 0xae, 0x74, 0x2b, 0xdb, 0xed, 0xfe, 0x66,
+	0xaf, 0xf8, 0x96, 0xde, 0xde, 0x1c, 0xe0, 0x17, 0xd1, 0xe4, 0x13, 0x80, 0x30, 0x15, 0x6c, 0x90,
+	0x04, 0x23, 0x9c, 0xba, 0x4d, 0x1d, 0xb9, 0x65, 0x34, 0x2f, 0x71, 0x4a, 0x6e, 0xc3, 0xc5, 0x13,
+	0xe4, 0xec, 0x78, 0x1a, 0x70, 0x7c, 0x3f, 0x61, 0x1c, 0x23, 0xb7, 0xa5, 0xb3, 0xba, 0x60, 0xd4,
+	0xbe, 0xd5, 0x7a, 0xbf, 0x57, 0xa1, 0x5d, 0xb8, 0x84, 0x6c, 0x41, 0x73, 0x22, 0x90, 0x17, 0x68,
+	0x9b, 0xc9, 0xca, 0x96, 0x51, 0x21, 0x4e, 0x53, 0x1e, 0x59, 0xda, 0x66, 0xb2, 0xa2, 0xf5, 0x08,
+	0x29, 0x47, 0x1e, 0xc8, 0x74, 0x84, 0x89, 0xa5, 0xad, 0x6d, 0x74, 0x07, 0x4a, 0x45, 0xee, 0xc1,
+	0x15, 0x05, 0x0f, 0x0a, 0xcf, 0x08, 0x68, 0x1c, 0x6b, 0x1a, 0x9b, 0x3e, 0x51, 0xb6, 0x42, 0x26,
+	0xbb, 0x71, 0x4c, 0x36, 0x60, 0x25, 0xa4, 0x41, 0x44, 0x25, 0xd5, 0x9c, 0x76, 0x7c, 0x27, 0xa4,
+	0xcf, 0xa8, 0xa4, 0xaa, 0x42, 0x21, 0x72, 0x69, 0x4c, 0x86, 0xd6, 0xa6, 0x52, 0x68, 0xe3, 0x26,
+	0x34, 0x47, 0x38, 0x35, 0xb6, 0x15, 0x43, 0xf9, 0x08, 0xa7, 0xda, 0xf4, 0x10, 0x5c, 0x96, 0x08,
+	0x0c, 0x27, 0x1c, 0x03, 0x31, 0x62, 0x59, 0x20, 0x63, 0x11, 0x18, 0x46, 0x34, 0x87, 0x4d, 0xff,
+	0x6a, 0x6e, 0xdf, 0x1f, 0xb1, 0xec, 0x20, 0x16, 0x87, 0xda, 0xa8, 0xe8, 0xce, 0x62, 0xca, 0x92,
+	0x60, 0x28, 0x65, 0x66, 0xa9, 0x6c, 0x69, 0xcd, 0xf7, 0x52, 0x66, 0xde, 0x1f, 0x15, 0x70, 0x0e,
+	0x69, 0x3c, 0x41, 0xa1, 0x7a, 0x6e, 0x4a, 0xc7, 0x71, 0xde, 0x73, 0xea, 0x4c, 0x2e, 0x41, 0x4d,
+	0xa0, 0x74, 0xab, 0xdd, 0xda, 0x76, 0xcb, 0x57, 0x47, 0x15, 0x4f, 0xa0, 0x0c, 0x84, 0xe4, 0x2c,
+	0x19, 0xb8, 0x35, 0x6d, 0x68, 0x09, 0x94, 0xfb, 0x5a, 0x41, 0x9e, 0x40, 0x53, 0x99, 0x8f, 0x59,
+	0x8c, 0x6e, 0xbd, 0x5b, 0xdb, 0x6e, 0xf7, 0x6f, 0x95, 0xfb, 0xc2, 0x5c, 0xd6, 0xdb, 0x47, 0xf9,
+	0x2d, 0x8b, 0xf1, 0x9b, 0x44, 0xf2, 0xa9, 0xbf, 0x22, 0x8c, 0xb4, 0xf5, 0x08, 0x3a, 0x45, 0x83,
+	0xba, 0x5e, 0x35, 0x89, 0xc9, 0x48, 0x1d, 0x55, 0xab, 0x9e, 0xa8, 0x08, 0xba, 0x8c, 0x1d, 0xdf,
+	0x08, 0x8f, 0xaa, 0x5f, 0x56, 0xbc, 0x5f, 0xea, 0xb0, 0xfa, 0x72, 0x22, 0x64, 0x3a, 0x66, 0x3f,
+	0x51, 0xa9, 0xda, 0xfa, 0x3a, 0xb4, 0x54, 0xf5, 0x45, 0x46, 0xc3, 0xbc, 0x25, 0xe6, 0x0a, 0xe2,
+	0xc3, 0x6a, 0x98, 0x8e, 0xc7, 0x69, 0x12, 0xc4, 0xf4, 0x08, 0x63, 0xa1, 0x1f, 0xd9, 0xee, 0x7f,
+	0x5e, 0x4e, 0xb7, 0x14, 0xb1, 0xb7, 0xa7, 0x1d, 0x5e, 0x69, 0xbc, 0x49, 0xbd, 0x13, 0x16, 0x54,
+	0x84, 0x02, 0xb1, 0x31, 0x69, 0x92, 0xa4, 0x52, 0x3b, 0x09, 0x4d, 0x52, 0xbb, 0xdf, 0xff, 0x78,
+	0xe0, 0xdd, 0xb9, 0x93, 0x89, 0xbe, 0x16, 0x2e, 0xea, 0xc9, 0x67, 0xe0, 0xb0, 0x31, 0x1d, 0xa0,
+	0xb0, 0xf4, 0x5e, 0x2e, 0x87, 0x7d, 0xae, 0x6c, 0xbe, 0x85, 0x90, 0x07, 0xb0, 0x91, 0x51, 0x19,
+	0x0e, 0x51, 0xa8, 0x82, 0x51, 0x89, 0x03, 0x16, 0x06, 0x63, 0xe4, 0x03, 0x74, 0x1b, 0xba, 0x72,
+	0x57, 0xad, 0x79, 0x3f, 0xb7, 0xbe, 0x56, 0x46, 0xf2, 0x14, 0x2e, 0xe5, 0x7e, 0xef, 0x44, 0x9a,
+	0x3c, 0xf8, 0xea, 0x5e, 0xdf, 0x75, 0xf4, 0x75, 0x1b, 0xe5, 0xeb, 0x5e, 0x88, 0x34, 0xf9, 0x51,
+	0x21, 0xfd, 0x8b, 0xd6, 0xe1, 0x85, 0xc5, 0x6f, 0x7d, 0x0d, 0x6b, 0x67, 0xe8, 0xfa, 0x58, 0x41,
+	0x5b, 0x85, 0x82, 0x6e, 0x3d, 0x83, 0xf5, 0xe5, 0xb4, 0xfc, 0x97, 0x28, 0xde, 0x00, 0x1a, 0x9a,
+	0x93, 0xa5, 0x2b, 0x75, 0x13, 0x9a, 0x09, 0x9e, 0x9a, 0x75, 0x69, 0x3c, 0x57, 0x12, 0x3c, 0xd5,
+	0xdb, 0x72, 0x03, 0xd4, 0x31, 0x90, 0x74, 0x60, 0x37, 0x82, 0x93, 0xe0, 0xe9, 0x01, 0x1d, 0x90,
+	0x75, 0x70, 0x22, 0x36, 0x40, 0x21, 0xed, 0x16, 0xb5, 0x92, 0x77, 0x00, 0xad, 0x19, 0x1b, 0xe4,
+	0x3e, 0x38, 0x92, 0xf2, 0x01, 0x4a, 0xb7, 0xb2, 0x6c, 0x39, 0x6a, 0xd0, 0x81, 0x06, 0xf8, 0x16,
+	0xa8, 0x9e, 0xa0, 0x29, 0xcc, 0x9f, 0xa0, 0x05, 0xef, 0xe7, 0x0a, 0xb4, 0x0b, 0x68, 0x85, 0x1a,
+	0xf0, 0x74, 0x92, 0xd9, 0x67, 0x18, 0xa1, 0xb8, 0xda, 0xab, 0xe5, 0xd5, 0x4e, 0xa0, 0x3e, 0x62,
+	0x49, 0x64, 0xdf, 0xa0, 0xcf, 0xe5, 0xb9, 0xa8, 0x2f, 0xce, 0x45, 0xce, 0x53, 0x63, 0xce, 0x93,
+	0xf7, 0x57, 0x15, 0x56, 0x7d, 0x4c, 0x22, 0xe4, 0x6a, 0xfd, 0xa2, 0x90, 0x8a, 0x05, 0x89, 0x09,
+	0x4d, 0xa4, 0x4d, 0xc4, 0x4a, 0xca, 0x5b, 0xbd, 0xd4, 0xa6, 0xa1, 0xcf, 0xe4, 0x0e, 0x34, 0x42,
+	0xf5, 0x55, 0xd3, 0x49, 0x9c, 0xe9, 0x58, 0xfd, 0xc1, 0xf3, 0x0d, 0xc2, 0x7c, 0xe3, 0x62, 0xa4,
+	0x02, 0x4d, 0x51, 0xea, 0xf9, 0x37, 0x4e, 0xeb, 0x74, 0x61, 0x4a, 0xd9, 0x37, 0x16, 0xb3, 0xbf,
+	0x00, 0xd5, 0xa1, 0x59, 0xac, 0x4d, 0xbf, 0x3a, 0xa4, 0x7a, 0xf3, 0xf3, 0x54, 0xad, 0x23, 0xf5,
+	0x9d, 0xaa, 0xe9, 0xcd, 0x6f, 0x65, 0x72, 0x17, 0x1c, 0xdd, 0x27, 0x42, 0x6f, 0xd0, 0x76, 0xff,
+	0xca, 0xb2, 0x4d, 0xe5, 0x5b, 0x8c, 0xe2, 0x38, 0xcd, 0xcc, 0x40, 0xb7, 0x0c, 0xc7, 0x56, 0x24,
+	0xbb, 0xb0, 0x3a, 0x2a, 0x4e, 0xb3, 0x0b, 0x3a, 0xdc, 0xb5, 0x0f, 0x0c, 0xbc, 0x5f, 0xf6, 0xf0,
+	0x7e, 0xad, 0x80, 0xf3, 0xe6, 0xe8, 0x1d, 0x86, 0x92, 0xdc, 0x84, 0x36, 0xcd, 0x58, 0x90, 0xd7,
+	0xd3, 0xd0, 0x0b, 0x34, 0x63, 0x87, 0x0b, 0x25, 0xad, 0x9e, 0x57, 0xd2, 0xda, 0x79, 0x25, 0xad,
+	0x17, 0x5a, 0x7f, 0x1d, 0x1c, 0x91, 0x4e, 0xf8, 0x8c, 0x43, 0x2b, 0xa9, 0xd9, 0xe2, 0xf4, 0x54,
+	0x33, 0xd8, 0xf2, 0xd5, 0xd1, 0x7b, 0x0b, 0xf0, 0x0c, 0x33, 0x55, 0xfd, 0x24, 0x9c, 0x2e, 0x1d,
+	0xa3, 0xf3, 0xdb, 0xef, 0x06, 0x80, 0xfa, 0xfd, 0x21, 0x98, 0x4c, 0xf9, 0xd4, 0x26, 0x56, 0xd0,
+	0x78, 0x7f, 0x57, 0xe0, 0x42, 0xde, 0x58, 0x22, 0x4b, 0x13, 0xa1, 0xbf, 0xd5, 0x63, 0x9a, 0xb0,
+	0x63, 0x35, 0x61, 0xf6, 0x3b, 0x9e, 0xcb, 0xa4, 0x07, 0x2b, 0xa9, 0x66, 0x29, 0xdf, 0xd6, 0x0b,
+	0x25, 0x33, 0x14, 0xfa, 0x39, 0xa8, 0x98, 0x58, 0xad, 0x9c, 0x58, 0xb1, 0x2f, 0xea, 0x0b, 0x7d,
+	0xf1, 0x04, 0x3a, 0x51, 0xfe, 0x60, 0x86, 0x42, 0xaf, 0xca, 0x76, 0xdf, 0x2d, 0x5f, 0x35, 0xa7,
+	0xc4, 0x2f, 0xa1, 0x15, 0xb1, 0xe9, 0x44, 0x66, 0x13, 0x69, 0x39, 0xb4, 0x92, 0x77, 0x19, 0xd6,
+	0x5e, 0x31, 0x21, 0x5f, 0xa3, 0x18, 0xa2, 0xb0, 0x63, 0xe4, 0xdd, 0x05, 0x52, 0x54, 0x5a, 0x0a,
+	0xd6, 0xc1, 0x19, 0x6b, 0x8d, 0x5b, 0xd1, 0xa9, 0x59, 0xc9, 0x7b, 0x0f, 0xae, 0x42, 0xeb, 0x89,
+	0xb1, 0xdd, 0x20, 0xfe, 0xdf, 0x81, 0xf4, 0x1e, 0xc2, 0xe6, 0x92, 0x2b, 0xe7, 0xa5, 0xb2, 0x7c,
+	0xe6, 0x99, 0xce, 0x64, 0x2f, 0x81, 0x4b, 0x87, 0x34, 0x66, 0x11, 0x95, 0x38, 0xc3, 0x9b, 0x2d,
+	0xcd, 0x22, 0x9d, 0x62, 0xd3, 0x37, 0x82, 0xd2, 0x22, 0xe7, 0x29, 0xcf, 0x17, 0x9f, 0x16, 0x8a,
+	0xa5, 0xae, 0xfd, 0x8b, 0x52, 0xf7, 0xff, 0xac, 0x42, 0x4b, 0xd1, 0xf8, 0x5c, 0x01, 0xc8, 0x1e,
+	0x38, 0xa6, 0xad, 0xc8, 0xc2, 0x14, 0x96, 0xb6, 0xd8, 0xd6, 0xf5, 0xe5, 0x46, 0x9b, 0xee, 0x1b,
+	0x80, 0x79, 0x71, 0xc8, 0xcd, 0x32, 0xf6, 0x4c, 0x2d, 0xb7, 0xba, 0xe7, 0x03, 0x6c, 0xc0, 0x08,
+	0xd6, 0xce, 0x90, 0x49, 0x3e, 0x3d, 0xeb, 0xb6, 0xac, 0xc0, 0x5b, 0xb7, 0x3f, 0x8a, 0xb3, 0xb7,
+	0x7c, 0x07, 0xcd, 0x9c, 0xf9, 0x0f, 0xbf, 0xfe, 0xc6, 0x99, 0x7d, 0x57, 0x2a, 0xd7, 0xd3, 0x3b,
+	0x6f, 0x6f, 0x0f, 0x98, 0x1c, 0x4e, 0x8e, 0x7a, 0x61, 0x3a, 0xde, 0xd9, 0x65, 0x62, 0x32, 0x4a,
+	0x77, 0x66, 0x2e, 0x3b, 0xd9, 0x68, 0xb0, 0x43, 0x33, 0xf6, 0x98, 0x66, 0xec, 0xc8, 0xd1, 0x7f,
+	0x67, 0xbe, 0xf8, 0x67, 0x00, 0x10, 0x83, 0x63, 0x01, 0xe1, 0x0c, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// MeshInfraClient is the client API for MeshInfra service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MeshInfraClient interface {
+	// Render renders the chart of a mesh
+	Render(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (*RenderResponse, error)
+	// ListMeshes lists the meshes the server renders
+	ListMeshes(ctx context.Context, in *ListMeshesRequest, opts ...grpc.CallOption) (*ListMeshesResponse, error)
+	// ListChartVersions lists the versions of a chart in its chart repo or
+	// registry
+	ListChartVersions(ctx context.Context, in *ListChartVersionsRequest, opts ...grpc.CallOption) (*ListChartVersionsResponse, error)
+	// Validate renders the chart of a mesh and reports whether the request is
+	// valid, the manifest is not returned
+	Validate(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (*ValidateResponse, error)
+}
+
+type meshInfraClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewMeshInfraClient(cc *grpc.ClientConn) MeshInfraClient {
+	return &meshInfraClient{cc}
+}
+
+func (c *meshInfraClient) Render(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (*RenderResponse, error) {
+	out := new(RenderResponse)
+	err := c.cc.Invoke(ctx, "/meshinfra.v1.MeshInfra/Render", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshInfraClient) ListMeshes(ctx context.Context, in *ListMeshesRequest, opts ...grpc.CallOption) (*ListMeshesResponse, error) {
+	out := new(ListMeshesResponse)
+	err := c.cc.Invoke(ctx, "/meshinfra.v1.MeshInfra/ListMeshes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshInfraClient) ListChartVersions(ctx context.Context, in *ListChartVersionsRequest, opts ...grpc.CallOption) (*ListChartVersionsResponse, error) {
+	out := new(ListChartVersionsResponse)
+	err := c.cc.Invoke(ctx, "/meshinfra.v1.MeshInfra/ListChartVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshInfraClient) Validate(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (*ValidateResponse, error) {
+	out := new(ValidateResponse)
+	err := c.cc.Invoke(ctx, "/meshinfra.v1.MeshInfra/Validate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MeshInfraServer is the server API for MeshInfra service.
+type MeshInfraServer interface {
+	// Render renders the chart of a mesh
+	Render(context.Context, *RenderRequest) (*RenderResponse, error)
+	// ListMeshes lists the meshes the server renders
+	ListMeshes(context.Context, *ListMeshesRequest) (*ListMeshesResponse, error)
+	// ListChartVersions lists the versions of a chart in its chart repo or
+	// registry
+	ListChartVersions(context.Context, *ListChartVersionsRequest) (*ListChartVersionsResponse, error)
+	// Validate renders the chart of a mesh and reports whether the request is
+	// valid, the manifest is not returned
+	Validate(context.Context, *RenderRequest) (*ValidateResponse, error)
+}
+
+// UnimplementedMeshInfraServer can be embedded to have forward compatible implementations.
+type UnimplementedMeshInfraServer struct {
+}
+
+func (*UnimplementedMeshInfraServer) Render(ctx context.Context, req *RenderRequest) (*RenderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Render not implemented")
+}
+func (*UnimplementedMeshInfraServer) ListMeshes(ctx context.Context, req *ListMeshesRequest) (*ListMeshesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMeshes not implemented")
+}
+func (*UnimplementedMeshInfraServer) ListChartVersions(ctx context.Context, req *ListChartVersionsRequest) (*ListChartVersionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListChartVersions not implemented")
+}
+func (*UnimplementedMeshInfraServer) Validate(ctx context.Context, req *RenderRequest) (*ValidateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Validate not implemented")
+}
+
+func RegisterMeshInfraServer(s *grpc.Server, srv MeshInfraServer) {
+	s.RegisterService(&_MeshInfra_serviceDesc, srv)
+}
+
+func _MeshInfra_Render_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshInfraServer).Render(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshinfra.v1.MeshInfra/Render",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshInfraServer).Render(ctx, req.(*RenderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshInfra_ListMeshes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMeshesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshInfraServer).ListMeshes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshinfra.v1.MeshInfra/ListMeshes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshInfraServer).ListMeshes(ctx, req.(*ListMeshesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshInfra_ListChartVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListChartVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshInfraServer).ListChartVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshinfra.v1.MeshInfra/ListChartVersions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshInfraServer).ListChartVersions(ctx, req.(*ListChartVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshInfra_Validate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshInfraServer).Validate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshinfra.v1.MeshInfra/Validate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshInfraServer).Validate(ctx, req.(*RenderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _MeshInfra_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "meshinfra.v1.MeshInfra",
+	HandlerType: (*MeshInfraServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Render",
+			Handler:    _MeshInfra_Render_Handler,
+		},
+		{
+			MethodName: "ListMeshes",
+			Handler:    _MeshInfra_ListMeshes_Handler,
+		},
+		{
+			MethodName: "ListChartVersions",
+			Handler:    _MeshInfra_ListChartVersions_Handler,
+		},
+		{
+			MethodName: "Validate",
+			Handler:    _MeshInfra_Validate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "meshinfra.proto",
+}
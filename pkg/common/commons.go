@@ -1,18 +1,10 @@
 package common
 
 import (
-	"fmt"
-
 	"github.com/pkg/errors"
 	"helm.sh/helm/v3/pkg/chart"
 )
 
-// Debug as a common tool function for transform function
-func Debug(format string, args ...interface{}) {
-	format = fmt.Sprintf("[debug] %s\n", format)
-	fmt.Printf(format, args...)
-}
-
 // IsChartInstallable is a tool function to check the chart can be used
 func IsChartInstallable(ch *chart.Chart) (bool, error) {
 	switch ch.Metadata.Type {
@@ -21,3 +13,9 @@ func IsChartInstallable(ch *chart.Chart) (bool, error) {
 	}
 	return false, errors.Errorf("%s charts are not installable", ch.Metadata.Type)
 }
+
+// MergeMaps is a tool function to deep merge the values b into the values a,
+// the values of b win on conflicts, see MergeValues
+func MergeMaps(a, b map[string]interface{}) map[string]interface{} {
+	return MergeValues(a, b)
+}
@@ -0,0 +1,63 @@
+package common
+
+import "fmt"
+
+// MergeValues is used to merge the layers of chart values, every layer wins
+// over the previous ones. The pipeline layers them as
+//
+//	chart defaults < profile files < mesh values < values files < --set
+//
+// Maps are merged deeply, any other value, lists included, replaces the value
+// of the lower layers. A null value deletes the key of the lower layers like
+// helm does; it is kept in the result so that helm also deletes the default
+// of the chart. The maps decoded by yaml.v2 with interface{} keys are merged
+// as well, the result only holds map[string]interface{} maps and the layers
+// are never changed.
+func MergeValues(layers ...map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, layer := range layers {
+		mergeInto(out, layer)
+	}
+	return out
+}
+
+// mergeInto merges the values of src into dst, dst is owned by the merge
+func mergeInto(dst, src map[string]interface{}) {
+	for k, v := range src {
+		v = normalizeValue(v)
+		if m, ok := v.(map[string]interface{}); ok {
+			if dm, ok := dst[k].(map[string]interface{}); ok {
+				mergeInto(dm, m)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// normalizeValue returns a deep copy of the value with the yaml.v2 maps
+// converted to map[string]interface{}
+func normalizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = normalizeValue(item)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[fmt.Sprint(k)] = normalizeValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = normalizeValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
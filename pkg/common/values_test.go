@@ -0,0 +1,117 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers []map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name: "no layers",
+			want: map[string]interface{}{},
+		},
+		{
+			name: "later layers win",
+			layers: []map[string]interface{}{
+				{"replicas": 1, "image": "mesh:1.0.0"},
+				{"replicas": 3},
+				{"replicas": 5},
+			},
+			want: map[string]interface{}{"replicas": 5, "image": "mesh:1.0.0"},
+		},
+		{
+			name: "maps are merged deeply",
+			layers: []map[string]interface{}{
+				{"controller": map[string]interface{}{"replicas": 1, "resources": map[string]interface{}{"cpu": "100m"}}},
+				{"controller": map[string]interface{}{"resources": map[string]interface{}{"memory": "64Mi"}}},
+			},
+			want: map[string]interface{}{
+				"controller": map[string]interface{}{
+					"replicas":  1,
+					"resources": map[string]interface{}{"cpu": "100m", "memory": "64Mi"},
+				},
+			},
+		},
+		{
+			name: "lists are replaced",
+			layers: []map[string]interface{}{
+				{"args": []interface{}{"a", "b"}},
+				{"args": []interface{}{"c"}},
+			},
+			want: map[string]interface{}{"args": []interface{}{"c"}},
+		},
+		{
+			name: "a value replaces a map",
+			layers: []map[string]interface{}{
+				{"tls": map[string]interface{}{"enabled": true}},
+				{"tls": "off"},
+			},
+			want: map[string]interface{}{"tls": "off"},
+		},
+		{
+			name: "null deletes the key",
+			layers: []map[string]interface{}{
+				{"controller": map[string]interface{}{"replicas": 1, "affinity": map[string]interface{}{"zone": "a"}}},
+				{"controller": map[string]interface{}{"affinity": nil}},
+			},
+			want: map[string]interface{}{
+				"controller": map[string]interface{}{"replicas": 1, "affinity": nil},
+			},
+		},
+		{
+			name: "a deleted key can be set again",
+			layers: []map[string]interface{}{
+				{"affinity": map[string]interface{}{"zone": "a"}},
+				{"affinity": nil},
+				{"affinity": map[string]interface{}{"region": "eu"}},
+			},
+			want: map[string]interface{}{"affinity": map[string]interface{}{"region": "eu"}},
+		},
+		{
+			name: "yaml.v2 maps",
+			layers: []map[string]interface{}{
+				{"controller": map[interface{}]interface{}{"replicas": 1, "image": "mesh:1.0.0"}},
+				{"controller": map[interface{}]interface{}{
+					"replicas": 3,
+					"ports":    []interface{}{map[interface{}]interface{}{"port": 80}},
+				}},
+			},
+			want: map[string]interface{}{
+				"controller": map[string]interface{}{
+					"replicas": 3,
+					"image":    "mesh:1.0.0",
+					"ports":    []interface{}{map[string]interface{}{"port": 80}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeValues(tt.layers...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeValues returned %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeValuesKeepsLayers(t *testing.T) {
+	lower := map[string]interface{}{"controller": map[string]interface{}{"replicas": 1}}
+	upper := map[string]interface{}{"controller": map[string]interface{}{"image": "mesh:1.0.0"}}
+
+	got := MergeValues(lower, upper)
+	got["controller"].(map[string]interface{})["replicas"] = 5
+
+	if !reflect.DeepEqual(lower, map[string]interface{}{"controller": map[string]interface{}{"replicas": 1}}) {
+		t.Errorf("the lower layer was changed: %v", lower)
+	}
+	if !reflect.DeepEqual(upper, map[string]interface{}{"controller": map[string]interface{}{"image": "mesh:1.0.0"}}) {
+		t.Errorf("the upper layer was changed: %v", upper)
+	}
+}
@@ -1,24 +1,95 @@
 package consul
 
 import (
+	"context"
 	"fmt"
+	"regexp"
 	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/Aisuko/meshinfra/pkg/transformer/transformertest"
 )
 
 var (
 	chartName        = "consul"
-	releaseName      = "consul-0.19.0"
+	releaseName      = "consul"
+	chartVersion     = "0.19.0"
 	namespace        = "default"
 	repoName         = "incubator"
 	chartRepoAddress = "https://aisuko.github.io/adapter-charts/incubator"
-	args             = map[string]string{}
+	overrides        = transformer.Values{}
 	isHa             = false
 )
 
-func TestExeTransformConsul(t *testing.T) {
-	strManifest, err := ExeTransformConsul(chartName, releaseName, namespace, repoName, chartRepoAddress, isHa, args)
+func TestTransformConsul(t *testing.T) {
+	result, err := transformer.Transform(context.Background(), Name, &transformer.Request{
+		ChartName:        chartName,
+		ReleaseName:      releaseName,
+		Namespace:        namespace,
+		RepoName:         repoName,
+		ChartRepoAddress: chartRepoAddress,
+		Version:          chartVersion,
+		Values:           overrides,
+		IsHa:             isHa,
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	fmt.Println(strManifest)
+	fmt.Println(result.Manifest)
+}
+
+var (
+	replicasPattern        = regexp.MustCompile(`replicas: (\d+)`)
+	bootstrapExpectPattern = regexp.MustCompile(`-bootstrap-expect=(\d+)`)
+)
+
+// checkRaft checks the raft cluster expects as many servers as there are
+// replicas
+func checkRaft(t *testing.T, result *transformer.Result) {
+	replicas := replicasPattern.FindStringSubmatch(result.Manifest)
+	expect := bootstrapExpectPattern.FindStringSubmatch(result.Manifest)
+	if replicas == nil || expect == nil || replicas[1] != expect[1] {
+		t.Errorf("the raft cluster does not expect the replicas %v, it expects %v", replicas, expect)
+	}
+}
+
+func TestTransformConsulLocalChart(t *testing.T) {
+	transformertest.Run(t, Name, transformer.Request{ReleaseName: "consul", Namespace: "consul", ChartPath: "testdata/consul"}, []transformertest.Case{
+		{
+			Name:    "default",
+			Want:    []string{"namespace: consul", "replicas: 1", "-bootstrap-expect=1"},
+			NotWant: []string{"podAntiAffinity"},
+			Check:   checkRaft,
+		},
+		{
+			Name:  "ha",
+			IsHa:  true,
+			Want:  []string{"replicas: 3", "-bootstrap-expect=3", "podAntiAffinity", "app: consul"},
+			Check: checkRaft,
+		},
+		{
+			Name:  "overrides win over ha",
+			Set:   []string{"server.replicas=5,global.datacenter=dc2"},
+			IsHa:  true,
+			Want:  []string{"replicas: 5", "-bootstrap-expect=5", "-datacenter=dc2"},
+			Check: checkRaft,
+		},
+		{
+			Name:  "replicas without ha",
+			Set:   []string{"server.replicas=2"},
+			Want:  []string{"replicas: 2", "-bootstrap-expect=2"},
+			Check: checkRaft,
+		},
+		{
+			// The overrides may set the expected servers on their own
+			Name: "explicit bootstrap expect",
+			Set:  []string{"server.bootstrapExpect=1"},
+			IsHa: true,
+			Want: []string{"replicas: 3", "-bootstrap-expect=1"},
+		},
+	})
+}
+
+func TestConsulValues(t *testing.T) {
+	transformertest.CheckValues(t, "testdata/consul", haValues())
 }
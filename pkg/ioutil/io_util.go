@@ -18,21 +18,18 @@ import (
 	"io"
 
 	"github.com/gofrs/flock"
-	"github.com/sirupsen/logrus"
 )
 
 // SafeClose is a helper function help to close the io
 func SafeClose(co io.Closer, err *error) {
 	if cerr := co.Close(); cerr != nil && *err == nil {
 		*err = cerr
-		logrus.Error(cerr)
 	}
 }
 
-// SafeUnLock help safely unlock the file and log the error to stoutput
+// SafeUnLock help safely unlock the file, the error is kept unless err holds one
 func SafeUnLock(locker *flock.Flock, err *error) {
 	if uerr := locker.Unlock(); uerr != nil && *err == nil {
 		*err = uerr
-		logrus.Error(uerr)
 	}
 }
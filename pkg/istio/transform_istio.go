@@ -0,0 +1,276 @@
+package istio
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"strings"
+
+	common "github.com/Aisuko/meshinfra/pkg/common"
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// Name is the mesh name the istio transformer is registered with
+const Name = "istio"
+
+// The profiles decide which istio charts are rendered and how
+const (
+	// ProfileDefault renders the base, istiod and gateway charts
+	ProfileDefault = "default"
+	// ProfileDemo is the default profile with access logs and full tracing
+	ProfileDemo = "demo"
+	// ProfileMinimal renders the base and istiod charts only
+	ProfileMinimal = "minimal"
+)
+
+// defaultNamespace is the namespace istio is rendered into when the request
+// does not set one
+const defaultNamespace = "istio-system"
+
+// Options are the istio specific options of a request
+type Options struct {
+	// Revision is the revision of the control plane, the istiod and gateway
+	// objects are labeled with it and istiod is named after it
+	Revision string
+	// Profile is one of the profiles, default is ProfileDefault
+	Profile string
+	// Base, Istiod and Gateway are the value overrides and the profiles of
+	// the charts, the ones of the request win over them
+	Base    ComponentOptions
+	Istiod  ComponentOptions
+	Gateway ComponentOptions
+}
+
+// ComponentOptions are the value overrides and the profiles of an istio chart
+type ComponentOptions struct {
+	// Values are the value overrides of the chart
+	Values transformer.Values
+	// Profiles select the values files shipped in the chart, the applied files
+	// are reported with the chart name like istiod/values-ha.yaml
+	Profiles []string
+}
+
+// Files returns the values files and the --set-file paths of the value
+// overrides of the charts, the server reads no files on behalf of a request
+func (o Options) Files() []string {
+	var files []string
+	for _, c := range []ComponentOptions{o.Base, o.Istiod, o.Gateway} {
+		files = append(files, c.Values.ValueFiles...)
+		files = append(files, c.Values.SetFile...)
+	}
+	return files
+}
+
+// component is one of the istio charts
+type component struct {
+	chart   string
+	release string
+	// values returns the values of the chart for the options and the HA
+	// request
+	values func(opts *Options, ha bool) map[string]interface{}
+	// overrides returns the value overrides and the profiles of the chart
+	overrides func(opts *Options) ComponentOptions
+}
+
+var (
+	base = component{
+		chart:   "base",
+		release: "istio-base",
+		// The base chart holds the CRDs and the cluster roles, it has no HA
+		values:    func(*Options, bool) map[string]interface{} { return map[string]interface{}{} },
+		overrides: func(opts *Options) ComponentOptions { return opts.Base },
+	}
+	istiod = component{
+		chart:     "istiod",
+		release:   "istiod",
+		values:    istiodValues,
+		overrides: func(opts *Options) ComponentOptions { return opts.Istiod },
+	}
+	gateway = component{
+		chart:     "gateway",
+		release:   "istio-ingressgateway",
+		values:    gatewayValues,
+		overrides: func(opts *Options) ComponentOptions { return opts.Gateway },
+	}
+)
+
+// profiles are the components of every profile, in the order they are rendered
+var profiles = map[string][]component{
+	ProfileDefault: {base, istiod, gateway},
+	ProfileDemo:    {base, istiod, gateway},
+	ProfileMinimal: {base, istiod},
+}
+
+type istio struct{}
+
+func init() {
+	transformer.Register(Name, &istio{})
+}
+
+// Transform renders the istio charts of the profile into a single manifest.
+// The charts are taken from the chart repo of the request, or from the base,
+// istiod and gateway directories of the ChartPath of the request. The HA
+// request runs two replicas of istiod and of the gateway at least. The value
+// overrides and the profiles of the request name their chart first, like
+// istiod.pilot.traceSampling=10 or istiod/tracing.
+func (i *istio) Transform(ctx context.Context, req *transformer.Request) (*transformer.Result, error) {
+	opts, err := options(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Chart != nil {
+		return nil, errors.New("istio is rendered from several charts, a loaded chart is not supported")
+	}
+
+	components, ok := profiles[opts.Profile]
+	if !ok {
+		return nil, errors.Errorf("unknown istio profile %q", opts.Profile)
+	}
+	overrides, err := requestOverrides(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &transformer.Result{}
+	for _, c := range components {
+		r, err := c.transform(ctx, req, opts, overrides[c.chart])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed rendering the istio %s chart", c.chart)
+		}
+		result.Manifest += r.Manifest
+		result.Objects = append(result.Objects, r.Objects...)
+		result.Hooks = append(result.Hooks, r.Hooks...)
+		for _, profile := range r.Profiles {
+			result.Profiles = append(result.Profiles, path.Join(c.chart, profile))
+		}
+		result.Releases = append(result.Releases, r.Release)
+		if r.Lock != nil {
+			if result.Lock == nil {
+				result.Lock = &chart.Lock{Generated: r.Lock.Generated}
+			}
+			result.Lock.Dependencies = append(result.Lock.Dependencies, r.Lock.Dependencies...)
+		}
+		// The istio charts are released together
+		result.Version = r.Version
+	}
+	return result, nil
+}
+
+// overrides are the value overrides and the profiles of the request for a chart
+type overrides struct {
+	values   map[string]interface{}
+	profiles []string
+}
+
+// requestOverrides returns the value overrides and the profiles of the request
+// by chart, the top level keys of the values and the profiles name the chart
+func requestOverrides(ctx context.Context, req *transformer.Request) (map[string]*overrides, error) {
+	byChart := map[string]*overrides{}
+	for _, c := range []component{base, istiod, gateway} {
+		byChart[c.chart] = &overrides{}
+	}
+
+	vals, err := transformer.MergeValues(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for key, v := range vals {
+		o, ok := byChart[key]
+		if !ok {
+			return nil, errors.Errorf("the istio value %q names no chart, the values are set per chart like istiod.pilot.traceSampling", key)
+		}
+		if o.values, ok = v.(map[string]interface{}); !ok {
+			return nil, errors.Errorf("the istio values of the %s chart are not a map", key)
+		}
+	}
+
+	for _, profile := range req.Profiles {
+		parts := strings.SplitN(profile, "/", 2)
+		o, ok := byChart[parts[0]]
+		if len(parts) != 2 || !ok {
+			return nil, errors.Errorf("the istio profile %q names no chart, the profiles are set per chart like istiod/ha", profile)
+		}
+		o.profiles = append(o.profiles, parts[1])
+	}
+	return byChart, nil
+}
+
+// transform renders the chart of the component, the value overrides of the
+// options win over the values of the component and the ones of the request
+// over both
+func (c component) transform(ctx context.Context, req *transformer.Request, opts *Options, reqOverrides *overrides) (*transformer.Result, error) {
+	creq := *req
+	creq.ChartName = c.chart
+	creq.ReleaseName = c.release
+	overrides := c.overrides(opts)
+	creq.Values = overrides.Values
+	creq.Profiles = append(append([]string(nil), overrides.Profiles...), reqOverrides.profiles...)
+	if c.chart == istiod.chart && opts.Revision != "" {
+		creq.ReleaseName = c.release + "-" + opts.Revision
+	}
+	if creq.Namespace == "" {
+		creq.Namespace = defaultNamespace
+	}
+	if req.ChartPath != "" {
+		creq.ChartPath = filepath.Join(req.ChartPath, c.chart)
+	}
+
+	h := &transformer.Helm{
+		Values: func(_ *transformer.Request, _ *chart.Chart, vals map[string]interface{}) (map[string]interface{}, error) {
+			return common.MergeValues(c.values(opts, req.IsHa), vals, reqOverrides.values), nil
+		},
+	}
+	return h.Transform(ctx, &creq)
+}
+
+// options returns the istio options of the request with the defaults applied
+func options(req *transformer.Request) (*Options, error) {
+	opts := &Options{}
+	if err := transformer.MeshOptions(req, Name, opts); err != nil {
+		return nil, err
+	}
+
+	if opts.Profile == "" {
+		opts.Profile = ProfileDefault
+	}
+	return opts, nil
+}
+
+// haReplicas are the replicas of istiod and of the gateway of the HA request
+const haReplicas = 2
+
+func istiodValues(opts *Options, ha bool) map[string]interface{} {
+	vals := map[string]interface{}{}
+	pilot := map[string]interface{}{}
+	if ha {
+		pilot["replicaCount"] = haReplicas
+		pilot["autoscaleMin"] = haReplicas
+	}
+	if opts.Revision != "" {
+		vals["revision"] = opts.Revision
+	}
+	if opts.Profile == ProfileDemo {
+		vals["meshConfig"] = map[string]interface{}{
+			"accessLogFile": "/dev/stdout",
+		}
+		pilot["traceSampling"] = 100.0
+	}
+	if len(pilot) != 0 {
+		vals["pilot"] = pilot
+	}
+	return vals
+}
+
+func gatewayValues(opts *Options, ha bool) map[string]interface{} {
+	vals := map[string]interface{}{}
+	if ha {
+		vals["replicaCount"] = haReplicas
+		vals["autoscaling"] = map[string]interface{}{"minReplicas": haReplicas}
+	}
+	if opts.Revision != "" {
+		vals["revision"] = opts.Revision
+	}
+	return vals
+}
@@ -0,0 +1,196 @@
+package istio
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/Aisuko/meshinfra/pkg/transformer/registrytest"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+func TestTransformIstio(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  interface{}
+		values   transformer.Values
+		profiles []string
+		ha       bool
+		objects  []string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:    "default",
+			objects: []string{"ServiceAccount/istio-reader-service-account", "ConfigMap/istio", "Deployment/istiod", "HorizontalPodAutoscaler/istiod", "Deployment/istio-ingressgateway", "HorizontalPodAutoscaler/istio-ingressgateway"},
+			want:    []string{"istio.io/rev: default", `accessLogFile: ""`, "minReplicas: 1"},
+			notWant: []string{"minReplicas: 2"},
+		},
+		{
+			name:    "ha",
+			ha:      true,
+			objects: []string{"ServiceAccount/istio-reader-service-account", "ConfigMap/istio", "Deployment/istiod", "HorizontalPodAutoscaler/istiod", "Deployment/istio-ingressgateway", "HorizontalPodAutoscaler/istio-ingressgateway"},
+			want:    []string{"minReplicas: 2"},
+			notWant: []string{"minReplicas: 1"},
+		},
+		{
+			name: "component overrides",
+			options: &Options{
+				Istiod:  ComponentOptions{Values: transformer.Values{Raw: map[string]interface{}{"pilot": map[string]interface{}{"autoscaleEnabled": false}}}, Profiles: []string{"tracing"}},
+				Gateway: ComponentOptions{Values: transformer.Values{Set: []string{"autoscaling.enabled=false", "replicaCount=3"}}},
+			},
+			ha:      true,
+			objects: []string{"ServiceAccount/istio-reader-service-account", "ConfigMap/istio", "Deployment/istiod", "Deployment/istio-ingressgateway"},
+			want:    []string{"replicas: 2", "replicas: 3", `value: "50"`},
+		},
+		{
+			name:     "request overrides",
+			options:  &Options{Gateway: ComponentOptions{Values: transformer.Values{Set: []string{"autoscaling.enabled=false", "replicaCount=3"}}}},
+			values:   transformer.Values{Set: []string{"istiod.pilot.autoscaleEnabled=false", "gateway.replicaCount=4"}},
+			profiles: []string{"istiod/tracing"},
+			ha:       true,
+			objects:  []string{"ServiceAccount/istio-reader-service-account", "ConfigMap/istio", "Deployment/istiod", "Deployment/istio-ingressgateway"},
+			want:     []string{"replicas: 2", "replicas: 4", `value: "50"`},
+			notWant:  []string{"replicas: 3"},
+		},
+		{
+			name:    "minimal",
+			options: Options{Profile: ProfileMinimal},
+			objects: []string{"ServiceAccount/istio-reader-service-account", "ConfigMap/istio", "Deployment/istiod", "HorizontalPodAutoscaler/istiod"},
+		},
+		{
+			name:    "demo",
+			options: &Options{Profile: ProfileDemo},
+			objects: []string{"ServiceAccount/istio-reader-service-account", "ConfigMap/istio", "Deployment/istiod", "HorizontalPodAutoscaler/istiod", "Deployment/istio-ingressgateway", "HorizontalPodAutoscaler/istio-ingressgateway"},
+			want:    []string{`accessLogFile: "/dev/stdout"`, `value: "100"`},
+		},
+		{
+			name:    "revision",
+			options: &Options{Revision: "canary"},
+			objects: []string{"ServiceAccount/istio-reader-service-account", "ConfigMap/istio-canary", "Deployment/istiod-canary", "HorizontalPodAutoscaler/istiod-canary", "Deployment/istio-ingressgateway", "HorizontalPodAutoscaler/istio-ingressgateway"},
+			want:    []string{"istio.io/rev: canary"},
+			notWant: []string{"istio.io/rev: default"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := transformer.Transform(context.Background(), Name, &transformer.Request{
+				ChartPath: "testdata/charts",
+				IsHa:      tt.ha,
+				Values:    tt.values,
+				Profiles:  tt.profiles,
+				Options:   tt.options,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var objects []string
+			for _, obj := range result.Objects {
+				objects = append(objects, obj.Kind+"/"+obj.Name)
+				if obj.Namespace != defaultNamespace {
+					t.Errorf("%s/%s is rendered into the %q namespace", obj.Kind, obj.Name, obj.Namespace)
+				}
+			}
+			if !reflect.DeepEqual(objects, tt.objects) {
+				t.Errorf("Transform returned the objects %v, want %v", objects, tt.objects)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(result.Manifest, want) {
+					t.Errorf("manifest does not contain %q:\n%s", want, result.Manifest)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(result.Manifest, notWant) {
+					t.Errorf("manifest should not contain %q:\n%s", notWant, result.Manifest)
+				}
+			}
+		})
+	}
+}
+
+func TestTransformIstioInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		req  transformer.Request
+	}{
+		{"invalid options", transformer.Request{Options: "demo"}},
+		{"unknown profile", transformer.Request{Options: Options{Profile: "unknown"}}},
+		{"request values without a chart", transformer.Request{Values: transformer.Values{Set: []string{"pilot.replicaCount=3"}}}},
+		{"request values of an unknown chart", transformer.Request{Values: transformer.Values{Set: []string{"cni.enabled=true"}}}},
+		{"request profiles without a chart", transformer.Request{Profiles: []string{"ha"}}},
+		{"missing chart profile", transformer.Request{Options: Options{Base: ComponentOptions{Profiles: []string{"tracing"}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.ChartPath = "testdata/charts"
+			if _, err := transformer.Transform(context.Background(), Name, &req); err == nil {
+				t.Error("Transform should fail")
+			}
+		})
+	}
+}
+
+func TestTransformIstioResult(t *testing.T) {
+	result, err := transformer.Transform(context.Background(), Name, &transformer.Request{
+		ChartPath: "testdata/charts",
+		Options:   Options{Istiod: ComponentOptions{Profiles: []string{"tracing"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"istiod/values-tracing.yaml"}; !reflect.DeepEqual(result.Profiles, want) {
+		t.Errorf("Transform applied the profiles %v, want %v", result.Profiles, want)
+	}
+	var releases []string
+	for _, rel := range result.Releases {
+		releases = append(releases, rel.Name)
+	}
+	if want := []string{"istio-base", "istiod", "istio-ingressgateway"}; !reflect.DeepEqual(releases, want) {
+		t.Errorf("Transform returned the releases %v, want %v", releases, want)
+	}
+	if result.Lock == nil || len(result.Lock.Dependencies) != 1 || result.Lock.Dependencies[0].Name != "common" {
+		t.Errorf("Transform returned the lock %v", result.Lock)
+	}
+}
+
+func TestTransformIstioOCI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	reg := registrytest.New()
+	defer reg.Close()
+	for _, c := range []string{"base", "istiod", "gateway"} {
+		ch, err := loader.Load(filepath.Join("testdata/charts", c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := reg.Push("istio", ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := transformer.Transform(context.Background(), Name, &transformer.Request{
+		ChartRepoAddress: reg.Address("istio"),
+		Registry:         transformer.RegistryOptions{PlainHTTP: true},
+		Settings:         transformer.Settings{RepositoryCache: filepath.Join(dir, "repository")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 6 {
+		t.Errorf("Transform returned %d objects, want 6", len(result.Objects))
+	}
+}
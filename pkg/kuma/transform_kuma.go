@@ -0,0 +1,85 @@
+package kuma
+
+import (
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/pkg/errors"
+)
+
+// Name is the mesh name the kuma transformer is registered with
+const Name = "kuma"
+
+// The modes the kuma control plane can run in
+const (
+	// ModeStandalone runs a single zone control plane, it is the default
+	ModeStandalone = "standalone"
+	// ModeZone runs the control plane of a zone connected to a global control plane
+	ModeZone = "zone"
+	// ModeGlobal runs the global control plane of a multi-zone deployment
+	ModeGlobal = "global"
+)
+
+// Options are the kuma specific options of a request
+type Options struct {
+	// Mode is the mode of the control plane, default is ModeStandalone
+	Mode string
+	// Zone is the name of the zone, it is required by ModeZone
+	Zone string
+	// GlobalAddress is the KDS address of the global control plane, it is
+	// required by ModeZone
+	GlobalAddress string
+}
+
+func init() {
+	transformer.Register(Name, &transformer.Helm{Values: transformer.MeshValues(Name, haValues(), optionValues)})
+}
+
+// optionValues returns the values of the kuma options of the request
+func optionValues(req *transformer.Request) (map[string]interface{}, error) {
+	opts, err := options(req)
+	if err != nil {
+		return nil, err
+	}
+
+	controlPlane := map[string]interface{}{
+		"mode": opts.Mode,
+	}
+	if opts.Mode == ModeZone {
+		controlPlane["zone"] = opts.Zone
+		controlPlane["kdsGlobalAddress"] = opts.GlobalAddress
+	}
+	return map[string]interface{}{"controlPlane": controlPlane}, nil
+}
+
+// options returns the kuma options of the request with the defaults applied
+func options(req *transformer.Request) (*Options, error) {
+	opts := &Options{}
+	if err := transformer.MeshOptions(req, Name, opts); err != nil {
+		return nil, err
+	}
+
+	switch opts.Mode {
+	case "":
+		opts.Mode = ModeStandalone
+	case ModeStandalone, ModeGlobal:
+	case ModeZone:
+		if opts.Zone == "" || opts.GlobalAddress == "" {
+			return nil, errors.New("the zone and the global address are required by the kuma zone mode")
+		}
+	default:
+		return nil, errors.Errorf("unknown kuma mode %q", opts.Mode)
+	}
+	return opts, nil
+}
+
+// haValues returns the values of the HA profile, the control plane runs three
+// replicas guarded by a pod disruption budget
+func haValues() map[string]interface{} {
+	return map[string]interface{}{
+		"controlPlane": map[string]interface{}{
+			"replicas": 3,
+			"podDisruptionBudget": map[string]interface{}{
+				"enabled": true,
+			},
+		},
+	}
+}
@@ -0,0 +1,47 @@
+package kuma
+
+import (
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/Aisuko/meshinfra/pkg/transformer/transformertest"
+)
+
+func TestTransformKuma(t *testing.T) {
+	transformertest.Run(t, Name, transformer.Request{ReleaseName: "kuma", Namespace: "kuma-system", ChartPath: "testdata/kuma"}, []transformertest.Case{
+		{
+			Name:    "standalone",
+			Want:    []string{"replicas: 1", `value: "standalone"`},
+			NotWant: []string{"PodDisruptionBudget", "KUMA_MULTIZONE_ZONE_NAME"},
+		},
+		{
+			Name:    "zone",
+			Options: Options{Mode: ModeZone, Zone: "zone-1", GlobalAddress: "grpcs://global:5685"},
+			Want:    []string{`value: "zone"`, `value: "zone-1"`, `value: "grpcs://global:5685"`},
+		},
+		{
+			Name:    "global ha",
+			Options: &Options{Mode: ModeGlobal},
+			IsHa:    true,
+			Want:    []string{`value: "global"`, "replicas: 3", "PodDisruptionBudget"},
+		},
+		{
+			Name: "overrides win over ha",
+			Set:  []string{"controlPlane.replicas=5"},
+			IsHa: true,
+			Want: []string{"replicas: 5", "PodDisruptionBudget"},
+		},
+		{Name: "zone without a zone", Options: Options{Mode: ModeZone}, Err: true},
+		{Name: "unknown mode", Options: Options{Mode: "remote"}, Err: true},
+		{Name: "invalid options", Options: "zone", Err: true},
+	})
+}
+
+func TestKumaValues(t *testing.T) {
+	vals, err := optionValues(&transformer.Request{Options: Options{Mode: ModeZone, Zone: "zone-1", GlobalAddress: "grpcs://global:5685"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transformertest.CheckValues(t, "testdata/kuma", vals)
+	transformertest.CheckValues(t, "testdata/kuma", haValues())
+}
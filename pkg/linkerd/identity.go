@@ -0,0 +1,275 @@
+package linkerd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	common "github.com/Aisuko/meshinfra/pkg/common"
+	"github.com/pkg/errors"
+)
+
+// KeyECDSAP256 is an ECDSA key on the P-256 curve, it is the only key algorithm
+// the linkerd identity issuer accepts
+const KeyECDSAP256 = "ecdsa-p256"
+
+const (
+	defaultTrustDomain    = "cluster.local"
+	defaultAnchorValidity = Duration(10 * 365 * 24 * time.Hour)
+	defaultIssuerValidity = Duration(365 * 24 * time.Hour)
+)
+
+// Duration is a time.Duration which is encoded as text like "8760h", so it can
+// be set in the YAML or JSON of the options
+type Duration time.Duration
+
+// MarshalText encodes the duration like time.Duration.String
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText parses the duration with time.ParseDuration
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// IdentityOptions are the options of the generated identity certificates
+type IdentityOptions struct {
+	// TrustDomain is the trust domain of the mesh, default is "cluster.local"
+	TrustDomain string
+	// AnchorValidity is how long the trust anchor is valid, default is 10 years
+	AnchorValidity Duration
+	// IssuerValidity is how long the issuer certificate is valid, default is
+	// a year
+	IssuerValidity Duration
+	// KeyAlgorithm is the algorithm of both keys, KeyECDSAP256 which is the
+	// default
+	KeyAlgorithm string
+	// DNSNames are added to the SANs of both certificates, the identity name
+	// of the trust domain is always one of them
+	DNSNames []string
+}
+
+// Identity is the generated trust anchor and issuer of the linkerd identity
+type Identity struct {
+	// TrustDomain is the trust domain the certificates were generated for
+	TrustDomain string
+	// TrustAnchorPEM is the PEM encoded trust anchor certificate
+	TrustAnchorPEM []byte
+	// TrustAnchorKeyPEM is the PEM encoded private key of the trust anchor
+	TrustAnchorKeyPEM []byte
+	// IssuerPEM is the PEM encoded issuer certificate, signed by the anchor
+	IssuerPEM []byte
+	// IssuerKeyPEM is the PEM encoded private key of the issuer
+	IssuerKeyPEM []byte
+	// IssuerExpiry is when the issuer certificate expires
+	IssuerExpiry time.Time
+}
+
+// GenerateIdentity generates a trust anchor and an issuer certificate signed
+// by it, as expected by the identity service of linkerd
+func GenerateIdentity(opts IdentityOptions) (*Identity, error) {
+	if opts.TrustDomain == "" {
+		opts.TrustDomain = defaultTrustDomain
+	}
+	if opts.AnchorValidity == 0 {
+		opts.AnchorValidity = defaultAnchorValidity
+	}
+	if opts.IssuerValidity == 0 {
+		opts.IssuerValidity = defaultIssuerValidity
+	}
+	if opts.AnchorValidity < 0 || opts.IssuerValidity < 0 {
+		return nil, errors.New("the validity of the identity certificates must be positive")
+	}
+
+	name := "identity.linkerd." + opts.TrustDomain
+	dnsNames := append([]string{name}, opts.DNSNames...)
+	now := time.Now()
+
+	anchorKey, err := generateKey(opts.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	anchor := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              dnsNames,
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(time.Duration(opts.AnchorValidity)),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            1,
+	}
+	anchorDER, err := createCertificate(anchor, anchor, anchorKey, anchorKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating the trust anchor")
+	}
+	if anchor, err = x509.ParseCertificate(anchorDER); err != nil {
+		return nil, errors.Wrap(err, "failed parsing the trust anchor")
+	}
+
+	issuerKey, err := generateKey(opts.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	issuer := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              dnsNames,
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(time.Duration(opts.IssuerValidity)),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	if issuer.NotAfter.After(anchor.NotAfter) {
+		return nil, errors.New("the issuer certificate must not outlive the trust anchor")
+	}
+	issuerDER, err := createCertificate(issuer, anchor, issuerKey, anchorKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating the issuer certificate")
+	}
+
+	anchorKeyPEM, err := encodeKey(anchorKey)
+	if err != nil {
+		return nil, err
+	}
+	issuerKeyPEM, err := encodeKey(issuerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		TrustDomain:       opts.TrustDomain,
+		TrustAnchorPEM:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: anchorDER}),
+		TrustAnchorKeyPEM: anchorKeyPEM,
+		IssuerPEM:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issuerDER}),
+		IssuerKeyPEM:      issuerKeyPEM,
+		// The certificate keeps seconds only
+		IssuerExpiry: issuer.NotAfter.UTC().Truncate(time.Second),
+	}, nil
+}
+
+// mergeValues returns the chart values of the identity with the value overrides
+// over them, and whether the generated certificates are the ones of the values.
+// The issuer certificate of the values has to chain to the trust anchor of the
+// values and to match the issuer key, so an override of only some of them
+// fails. The issuer expiry is the one of the issuer certificate of the values,
+// an override of the expiry has to agree with it.
+func (i *Identity) mergeValues(overrides map[string]interface{}) (map[string]interface{}, bool, error) {
+	vals := common.MergeMaps(i.values(), overrides)
+	anchorsPEM, _ := nestedMap(vals, "global")["identityTrustAnchorsPEM"].(string)
+	issuer := nestedMap(vals, "identity", "issuer")
+	tlsVals := nestedMap(issuer, "tls")
+	crtPEM, ok := tlsVals["crtPEM"].(string)
+	if !ok {
+		return nil, false, errors.New("the issuer certificate is not a PEM string")
+	}
+	keyPEM, _ := tlsVals["keyPEM"].(string)
+	block, _ := pem.Decode([]byte(crtPEM))
+	if block == nil {
+		return nil, false, errors.New("no issuer certificate in the values")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed parsing the issuer certificate")
+	}
+	if _, err := tls.X509KeyPair([]byte(crtPEM), []byte(keyPEM)); err != nil {
+		return nil, false, errors.Wrap(err, "the issuer key does not match the issuer certificate")
+	}
+
+	anchors := x509.NewCertPool()
+	if !anchors.AppendCertsFromPEM([]byte(anchorsPEM)) {
+		return nil, false, errors.New("no trust anchor in the values")
+	}
+	// The chain is verified when the issuer was issued, its expiry is checked
+	// by the identity service
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:       anchors,
+		CurrentTime: cert.NotBefore,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, false, errors.Wrap(err, "the issuer certificate does not chain to the trust anchor")
+	}
+	expiry := cert.NotAfter.UTC()
+
+	if v, ok := nestedMap(overrides, "identity", "issuer")["crtExpiry"]; ok {
+		t, err := time.Parse(time.RFC3339, fmt.Sprint(v))
+		if err != nil || !t.Equal(expiry) {
+			return nil, false, errors.Errorf("the issuer expiry %v does not agree with the issuer certificate, which expires at %s", v, expiry.Format(time.RFC3339))
+		}
+	}
+	issuer["crtExpiry"] = expiry.Format(time.RFC3339)
+
+	generated := anchorsPEM == string(i.TrustAnchorPEM) && crtPEM == string(i.IssuerPEM) && keyPEM == string(i.IssuerKeyPEM)
+	return vals, generated, nil
+}
+
+// values returns the chart values of the identity
+func (i *Identity) values() map[string]interface{} {
+	return map[string]interface{}{
+		"global": map[string]interface{}{
+			"identityTrustDomain":     i.TrustDomain,
+			"identityTrustAnchorsPEM": string(i.TrustAnchorPEM),
+		},
+		"identity": map[string]interface{}{
+			"issuer": map[string]interface{}{
+				"crtExpiry": i.IssuerExpiry.Format(time.RFC3339),
+				"tls": map[string]interface{}{
+					"crtPEM": string(i.IssuerPEM),
+					"keyPEM": string(i.IssuerKeyPEM),
+				},
+			},
+		},
+	}
+}
+
+// nestedMap returns the map of the values at the path, nil when there is none
+func nestedMap(vals map[string]interface{}, path ...string) map[string]interface{} {
+	for _, key := range path {
+		vals, _ = vals[key].(map[string]interface{})
+	}
+	return vals
+}
+
+func generateKey(algorithm string) (*ecdsa.PrivateKey, error) {
+	if algorithm != "" && algorithm != KeyECDSAP256 {
+		return nil, errors.Errorf("unsupported key algorithm %q", algorithm)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed generating the key")
+	}
+	return key, nil
+}
+
+func createCertificate(template, parent *x509.Certificate, key *ecdsa.PrivateKey, signer crypto.Signer) ([]byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed generating the serial number")
+	}
+	template.SerialNumber = serial
+	return x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed encoding the key")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,139 @@
+package linkerd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+func parseCertificate(t *testing.T, data []byte) *x509.Certificate {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		t.Fatalf("no certificate in %q", data)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestGenerateIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     IdentityOptions
+		curve    elliptic.Curve
+		dnsName  string
+		validity time.Duration
+	}{
+		{
+			name:     "defaults",
+			curve:    elliptic.P256(),
+			dnsName:  "identity.linkerd.cluster.local",
+			validity: time.Duration(defaultIssuerValidity),
+		},
+		{
+			name: "custom",
+			opts: IdentityOptions{
+				TrustDomain:    "example.org",
+				AnchorValidity: Duration(48 * time.Hour),
+				IssuerValidity: Duration(24 * time.Hour),
+				KeyAlgorithm:   KeyECDSAP256,
+				DNSNames:       []string{"issuer.example.org"},
+			},
+			curve:    elliptic.P256(),
+			dnsName:  "issuer.example.org",
+			validity: 24 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := GenerateIdentity(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			anchor := parseCertificate(t, identity.TrustAnchorPEM)
+			issuer := parseCertificate(t, identity.IssuerPEM)
+			roots := x509.NewCertPool()
+			roots.AddCert(anchor)
+			if _, err := issuer.Verify(x509.VerifyOptions{DNSName: tt.dnsName, Roots: roots}); err != nil {
+				t.Fatalf("the issuer is not valid for %s: %v", tt.dnsName, err)
+			}
+			if !issuer.IsCA || !issuer.MaxPathLenZero {
+				t.Error("the issuer should be a CA without intermediates")
+			}
+			if !issuer.NotAfter.Equal(identity.IssuerExpiry) {
+				t.Errorf("the issuer expires at %v, want %v", issuer.NotAfter, identity.IssuerExpiry)
+			}
+			if d := time.Until(issuer.NotAfter); d > tt.validity || d < tt.validity-time.Minute {
+				t.Errorf("the issuer is valid for %v, want %v", d, tt.validity)
+			}
+
+			for _, pair := range []struct {
+				cert *x509.Certificate
+				key  []byte
+			}{{anchor, identity.TrustAnchorKeyPEM}, {issuer, identity.IssuerKeyPEM}} {
+				block, _ := pem.Decode(pair.key)
+				if block == nil || block.Type != "EC PRIVATE KEY" {
+					t.Fatalf("no EC private key in %q", pair.key)
+				}
+				key, err := x509.ParseECPrivateKey(block.Bytes)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if key.Curve != tt.curve {
+					t.Errorf("the key is on the %s curve, want %s", key.Curve.Params().Name, tt.curve.Params().Name)
+				}
+				pub := pair.cert.PublicKey.(*ecdsa.PublicKey)
+				if pub.X.Cmp(key.X) != 0 || pub.Y.Cmp(key.Y) != 0 {
+					t.Errorf("the key does not match the certificate of %s", pair.cert.Subject)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateIdentityInvalidOptions(t *testing.T) {
+	for _, opts := range []IdentityOptions{
+		{KeyAlgorithm: "rsa-2048"},
+		{KeyAlgorithm: "ecdsa-p384"},
+		{IssuerValidity: Duration(-time.Hour)},
+		{AnchorValidity: Duration(time.Hour), IssuerValidity: Duration(2 * time.Hour)},
+	} {
+		if _, err := GenerateIdentity(opts); err == nil {
+			t.Errorf("GenerateIdentity with the options %+v should fail", opts)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	var opts IdentityOptions
+	if err := yaml.UnmarshalStrict([]byte("anchorValidity: 87600h\nissuerValidity: 24h30m\n"), &opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.AnchorValidity != defaultAnchorValidity || time.Duration(opts.IssuerValidity) != 24*time.Hour+30*time.Minute {
+		t.Errorf("the validities are %v and %v", opts.AnchorValidity, opts.IssuerValidity)
+	}
+
+	data, err := yaml.Marshal(IdentityOptions{IssuerValidity: Duration(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "IssuerValidity: 1h0m0s") {
+		t.Errorf("the options are encoded as %s", data)
+	}
+
+	for _, data := range []string{"issuerValidity: 24", "issuerValidity: a day"} {
+		if err := yaml.UnmarshalStrict([]byte(data), &opts); err == nil {
+			t.Errorf("the options %q should be invalid", data)
+		}
+	}
+}
@@ -1,71 +1,248 @@
 package linkerd
 
 import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
-	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
 )
 
 var (
 	chartName        = "linkerd2"
-	releaseName      = "linkerd2-2.7.0"
+	releaseName      = "linkerd2"
+	chartVersion     = "2.7.0"
 	namespace        = "linkerd"
 	repoName         = "stable"
 	chartRepoAddress = "https://aisuko.github.io/adapter-charts/stable"
-	args             = map[string]string{
-		"--set":      "identity.issuer.crtExpiry",
-		"--set-file": "global.identityTrustAnchorsPEM,identity.issuer.tls.crtPEM,identity.issuer.tls.keyPEM",
+	overrides        = transformer.Values{
+		Set: []string{"identity.issuer.crtExpiry=2021-04-10T19:49:28Z"},
+		SetFile: []string{
+			"global.identityTrustAnchorsPEM=testdata/ca.crt",
+			"identity.issuer.tls.crtPEM=testdata/issuer.crt",
+			"identity.issuer.tls.keyPEM=testdata/issuer.key",
+		},
 	}
 	isHa = true
 )
 
 func TestTransformLinkerd(t *testing.T) {
-
-	wd, err := os.Getwd()
+	result, err := transformer.Transform(context.Background(), Name, &transformer.Request{
+		ChartName:        chartName,
+		ReleaseName:      releaseName,
+		Namespace:        namespace,
+		RepoName:         repoName,
+		ChartRepoAddress: chartRepoAddress,
+		Version:          chartVersion,
+		IsHa:             isHa,
+		Options:          &Options{Identity: &IdentityOptions{}},
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	fmt.Println(result.Manifest)
+	if !reflect.DeepEqual(result.Profiles, []string{"values-ha.yaml"}) {
+		t.Fatalf("Transform applied the profiles %v, want values-ha.yaml", result.Profiles)
+	} else {
+		fmt.Println("Linkerd was deployed with High-Availability scenario succeed")
+	}
 
-	pathCaCrt := wd + "/testdata/ca.crt"
+}
 
-	pathIssuerCrt := wd + "/testdata/issuer.crt"
+func TestTransformLinkerdLocalChart(t *testing.T) {
+	tests := []struct {
+		name     string
+		isHa     bool
+		profiles []string
+		replicas string
+		files    []string
+	}{
+		{name: "default", replicas: "replicas: 1"},
+		{name: "ha", isHa: true, replicas: "replicas: 3", files: []string{"values-ha.yaml"}},
+		{name: "ha profile", isHa: true, profiles: []string{"ha"}, replicas: "replicas: 3", files: []string{"values-ha.yaml"}},
+	}
 
-	pathIssuerKey := wd + "/testdata/issuer.key"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := transformer.Transform(context.Background(), Name, &transformer.Request{
+				ReleaseName: "linkerd2",
+				Namespace:   namespace,
+				ChartPath:   "testdata/linkerd2",
+				Values:      overrides,
+				IsHa:        tt.isHa,
+				Profiles:    tt.profiles,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
 
-	extraCert := &ExtraCert{}
-	extraCert.reader(pathCaCrt, t)
-	extraCert.reader(pathIssuerCrt, t)
-	extraCert.reader(pathIssuerKey, t)
+			for _, want := range []string{"namespace: linkerd", tt.replicas, "2021-04-10T19:49:28Z", "BEGIN CERTIFICATE"} {
+				if !strings.Contains(result.Manifest, want) {
+					t.Errorf("manifest does not contain %q:\n%s", want, result.Manifest)
+				}
+			}
+			if !reflect.DeepEqual(result.Profiles, tt.files) {
+				t.Errorf("Transform applied the profiles %v, want %v", result.Profiles, tt.files)
+			}
+		})
+	}
+}
 
-	args["--set"] = string("identity.issuer.crtExpiry=2021-04-10T19:49:28Z")
-	args["--set-file"] = fmt.Sprintf("global.identityTrustAnchorsPEM=%s,identity.issuer.tls.crtPEM=%s,identity.issuer.tls.keyPEM=%s", extraCert.bySlice[0], extraCert.bySlice[1], extraCert.bySlice[2])
+func TestTransformLinkerdCertificatesInMemory(t *testing.T) {
+	data := map[string][]byte{}
+	for key, file := range map[string]string{
+		"global.identityTrustAnchorsPEM": "testdata/ca.crt",
+		"identity.issuer.tls.crtPEM":     "testdata/issuer.crt",
+		"identity.issuer.tls.keyPEM":     "testdata/issuer.key",
+	} {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data[key] = b
+	}
 
-	fmt.Println(args["--set-file"])
+	result, err := transformer.Transform(context.Background(), Name, &transformer.Request{
+		ReleaseName: "linkerd2",
+		Namespace:   namespace,
+		ChartPath:   "testdata/linkerd2",
+		Values: transformer.Values{
+			Set:         overrides.Set,
+			SetFileData: data,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	strManifest, err := ExeTransformLinkerd(chartName, releaseName, namespace, repoName, chartRepoAddress, isHa, args)
+	issuer, err := ioutil.ReadFile("testdata/issuer.crt")
 	if err != nil {
 		t.Fatal(err)
 	}
-	fmt.Println(strManifest)
-	bol := strings.ContainsAny("values-ha.yaml", strManifest)
-	if !bol {
-		t.Fatal("Can not find the values-ha.yaml string")
-	} else {
-		fmt.Println("Linkerd was deployed with High-Availability scenario succeed")
+	for _, want := range []string{"BEGIN CERTIFICATE", "crt.pem: " + base64.StdEncoding.EncodeToString(issuer)} {
+		if !strings.Contains(result.Manifest, want) {
+			t.Errorf("manifest does not contain %q:\n%s", want, result.Manifest)
+		}
 	}
-
 }
 
-func (extracert *ExtraCert) reader(pathOfFile string, t *testing.T) {
-	byteContext, err := ioutil.ReadFile(pathOfFile)
-	if err != nil {
-		t.Fatal(err)
+func TestTransformLinkerdGeneratedIdentity(t *testing.T) {
+	data := map[string][]byte{}
+	for key, file := range map[string]string{
+		"global.identityTrustAnchorsPEM": "testdata/ca.crt",
+		"identity.issuer.tls.crtPEM":     "testdata/issuer.crt",
+		"identity.issuer.tls.keyPEM":     "testdata/issuer.key",
+	} {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data[key] = b
+	}
+
+	tests := []struct {
+		name   string
+		values transformer.Values
+		// issuer returns the issuer certificate of the manifest, the identity is
+		// nil unless the generated certificates are rendered
+		issuer func(identity *Identity) []byte
+		err    bool
+	}{
+		{
+			name:   "generated",
+			issuer: func(identity *Identity) []byte { return identity.IssuerPEM },
+		},
+		{
+			name: "issuer only",
+			values: transformer.Values{SetFileData: map[string][]byte{
+				"identity.issuer.tls.crtPEM": data["identity.issuer.tls.crtPEM"],
+				"identity.issuer.tls.keyPEM": data["identity.issuer.tls.keyPEM"],
+			}},
+			err: true,
+		},
+		{
+			name:   "trust anchor only",
+			values: transformer.Values{SetFileData: map[string][]byte{"global.identityTrustAnchorsPEM": data["global.identityTrustAnchorsPEM"]}},
+			err:    true,
+		},
+		{
+			name:   "issuer key only",
+			values: transformer.Values{SetFileData: map[string][]byte{"identity.issuer.tls.keyPEM": data["identity.issuer.tls.keyPEM"]}},
+			err:    true,
+		},
+		{
+			name:   "overrides win",
+			values: transformer.Values{SetFileData: data},
+			issuer: func(*Identity) []byte { return data["identity.issuer.tls.crtPEM"] },
+		},
+		{
+			name:   "expiry of the certificate",
+			values: transformer.Values{Set: []string{"identity.issuer.crtExpiry=2021-04-01T10:14:29Z"}, SetFileData: data},
+			issuer: func(*Identity) []byte { return data["identity.issuer.tls.crtPEM"] },
+		},
+		{
+			name:   "expiry which does not agree",
+			values: transformer.Values{Set: overrides.Set},
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := transformer.Transform(context.Background(), Name, &transformer.Request{
+				ReleaseName: "linkerd2",
+				Namespace:   namespace,
+				ChartPath:   "testdata/linkerd2",
+				Values:      tt.values,
+				Options:     &Options{Identity: &IdentityOptions{TrustDomain: "example.org"}},
+			})
+			if tt.err {
+				if err == nil {
+					t.Fatal("Transform should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			identity, _ := result.Output.(*Identity)
+			if generated := len(tt.values.SetFileData) == 0; (identity != nil) != generated {
+				t.Fatalf("the output is %T, want the identity %t", result.Output, generated)
+			}
+			issuer := tt.issuer(identity)
+			block, _ := pem.Decode(issuer)
+			if block == nil {
+				t.Fatalf("no issuer certificate in %q", issuer)
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{
+				"trustDomain: example.org",
+				"crt.pem: " + base64.StdEncoding.EncodeToString(issuer),
+				"linkerd.io/identity-issuer-expiry: " + cert.NotAfter.UTC().Format(time.RFC3339),
+			} {
+				if !strings.Contains(result.Manifest, want) {
+					t.Errorf("manifest does not contain %q:\n%s", want, result.Manifest)
+				}
+			}
+		})
 	}
-	extracert.bySlice = append(extracert.bySlice, string(byteContext))
 }
 
-type ExtraCert struct {
-	bySlice []string
+func TestTransformLinkerdInvalidOptions(t *testing.T) {
+	for _, options := range []interface{}{"identity", Options{Identity: &IdentityOptions{KeyAlgorithm: "rsa"}}} {
+		if _, err := transformer.Transform(context.Background(), Name, &transformer.Request{ChartPath: "testdata/linkerd2", Options: options}); err == nil {
+			t.Errorf("Transform with the options %v should fail", options)
+		}
+	}
 }
@@ -0,0 +1,41 @@
+// Package log is the structured logging of the transforms. The transforms log
+// nothing unless the caller hands them a Logger, like the logrus one:
+//
+//	req.Logger = log.NewLogrus(logrus.StandardLogger())
+package log
+
+// The fields the transforms add to their log entries
+const (
+	FieldMesh       = "mesh"
+	FieldChart      = "chart"
+	FieldRelease    = "release"
+	FieldRepository = "repository"
+	// FieldTenant is the tenant of the requests of the server
+	FieldTenant = "tenant"
+)
+
+// Fields are the structured fields of a log entry
+type Fields map[string]interface{}
+
+// Logger is used to log the steps of a transform with levels and fields
+type Logger interface {
+	// WithFields returns the logger adding the fields to every entry
+	WithFields(fields Fields) Logger
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Nop returns the logger dropping every entry
+func Nop() Logger {
+	return nop{}
+}
+
+type nop struct{}
+
+func (n nop) WithFields(Fields) Logger                { return n }
+func (nop) Debugf(format string, args ...interface{}) {}
+func (nop) Infof(format string, args ...interface{})  {}
+func (nop) Warnf(format string, args ...interface{})  {}
+func (nop) Errorf(format string, args ...interface{}) {}
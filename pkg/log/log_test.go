@@ -0,0 +1,38 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogrus(t *testing.T) {
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.Out = &buf
+	l.Formatter = &logrus.JSONFormatter{}
+	l.Level = logrus.InfoLevel
+
+	logger := NewLogrus(l).WithFields(Fields{FieldMesh: "linkerd"}).WithFields(Fields{FieldRelease: "linkerd2"})
+	logger.Debugf("dropped by the level")
+	logger.Warnf("rendered %d objects", 3)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	want := map[string]interface{}{"level": "warning", "msg": "rendered 3 objects", "mesh": "linkerd", "release": "linkerd2"}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("the %s of the entry is %v, want %v", k, entry[k], v)
+		}
+	}
+}
+
+func TestNop(t *testing.T) {
+	logger := Nop().WithFields(Fields{FieldChart: "consul"})
+	logger.Debugf("nothing")
+	logger.Errorf("nothing")
+}
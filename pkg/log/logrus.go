@@ -0,0 +1,32 @@
+package log
+
+import "github.com/sirupsen/logrus"
+
+// NewLogrus returns the logger writing to the logrus logger or entry
+func NewLogrus(l logrus.FieldLogger) Logger {
+	return &logrusLogger{l: l}
+}
+
+type logrusLogger struct {
+	l logrus.FieldLogger
+}
+
+func (l *logrusLogger) WithFields(fields Fields) Logger {
+	return &logrusLogger{l: l.l.WithFields(logrus.Fields(fields))}
+}
+
+func (l *logrusLogger) Debugf(format string, args ...interface{}) {
+	l.l.Debugf(format, args...)
+}
+
+func (l *logrusLogger) Infof(format string, args ...interface{}) {
+	l.l.Infof(format, args...)
+}
+
+func (l *logrusLogger) Warnf(format string, args ...interface{}) {
+	l.l.Warnf(format, args...)
+}
+
+func (l *logrusLogger) Errorf(format string, args ...interface{}) {
+	l.l.Errorf(format, args...)
+}
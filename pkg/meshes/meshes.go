@@ -0,0 +1,41 @@
+// Package meshes imports every mesh meshinfra supports, so their transformers
+// are registered, and decodes the mesh specific options of a request from YAML
+// for the command and the server.
+package meshes
+
+import (
+	"github.com/Aisuko/meshinfra/pkg/istio"
+	"github.com/Aisuko/meshinfra/pkg/kuma"
+	"github.com/Aisuko/meshinfra/pkg/linkerd"
+	"github.com/Aisuko/meshinfra/pkg/osm"
+	"github.com/Aisuko/meshinfra/pkg/traefikmesh"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	// consul has no options
+	_ "github.com/Aisuko/meshinfra/pkg/consul"
+)
+
+// options return the options of the meshes the YAML is decoded into, the
+// meshes which are missing have no options
+var options = map[string]func() interface{}{
+	istio.Name:       func() interface{} { return &istio.Options{} },
+	kuma.Name:        func() interface{} { return &kuma.Options{} },
+	linkerd.Name:     func() interface{} { return &linkerd.Options{} },
+	osm.Name:         func() interface{} { return &osm.Options{} },
+	traefikmesh.Name: func() interface{} { return &traefikmesh.Options{} },
+}
+
+// DecodeOptions decodes the YAML into the options of the mesh, the unknown
+// fields are an error
+func DecodeOptions(mesh string, data []byte) (interface{}, error) {
+	newOptions, ok := options[mesh]
+	if !ok {
+		return nil, errors.Errorf("the mesh %s has no options", mesh)
+	}
+	opts := newOptions()
+	if err := yaml.UnmarshalStrict(data, opts); err != nil {
+		return nil, errors.Wrapf(err, "failed parsing the %s options", mesh)
+	}
+	return opts, nil
+}
@@ -0,0 +1,31 @@
+package meshes
+
+import (
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/istio"
+)
+
+func TestDecodeOptions(t *testing.T) {
+	opts, err := DecodeOptions(istio.Name, []byte("profile: minimal\nrevision: canary\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o, ok := opts.(*istio.Options); !ok || o.Profile != "minimal" || o.Revision != "canary" {
+		t.Errorf("DecodeOptions returned %#v", opts)
+	}
+
+	for _, tt := range []struct {
+		name string
+		mesh string
+		data string
+	}{
+		{"unknown field", istio.Name, "unknown: true"},
+		{"invalid YAML", istio.Name, "profile: ["},
+		{"mesh without options", "consul", "profile: minimal"},
+	} {
+		if _, err := DecodeOptions(tt.mesh, []byte(tt.data)); err == nil {
+			t.Errorf("DecodeOptions of %s should fail", tt.name)
+		}
+	}
+}
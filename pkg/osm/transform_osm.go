@@ -0,0 +1,60 @@
+package osm
+
+import (
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+)
+
+// Name is the mesh name the open service mesh transformer is registered with
+const Name = "osm"
+
+// Options are the open service mesh specific options of a request, the zero
+// values keep the defaults of the chart
+type Options struct {
+	// MeshName is the name of the mesh instance
+	MeshName string
+	// PermissiveTrafficPolicy allows all the traffic within the mesh without
+	// any SMI traffic policy
+	PermissiveTrafficPolicy bool
+	// Egress allows the traffic to leave the mesh
+	Egress bool
+}
+
+func init() {
+	transformer.Register(Name, &transformer.Helm{Values: transformer.MeshValues(Name, haValues(), optionValues)})
+}
+
+// optionValues returns the values of the open service mesh options of the
+// request
+func optionValues(req *transformer.Request) (map[string]interface{}, error) {
+	opts := &Options{}
+	if err := transformer.MeshOptions(req, Name, opts); err != nil {
+		return nil, err
+	}
+
+	osm := map[string]interface{}{}
+	if opts.MeshName != "" {
+		osm["meshName"] = opts.MeshName
+	}
+	if opts.PermissiveTrafficPolicy {
+		osm["enablePermissiveTrafficPolicy"] = true
+	}
+	if opts.Egress {
+		osm["enableEgress"] = true
+	}
+	return map[string]interface{}{"osm": osm}, nil
+}
+
+// haValues returns the values of the HA profile, the controller runs three
+// replicas guarded by a pod disruption budget
+func haValues() map[string]interface{} {
+	return map[string]interface{}{
+		"osm": map[string]interface{}{
+			"osmController": map[string]interface{}{
+				"replicaCount": 3,
+				"podDisruptionBudget": map[string]interface{}{
+					"enabled": true,
+				},
+			},
+		},
+	}
+}
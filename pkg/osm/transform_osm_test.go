@@ -0,0 +1,44 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/Aisuko/meshinfra/pkg/transformer/transformertest"
+)
+
+func TestTransformOsm(t *testing.T) {
+	transformertest.Run(t, Name, transformer.Request{ReleaseName: "osm", Namespace: "osm-system", ChartPath: "testdata/osm"}, []transformertest.Case{
+		{
+			Name:    "default",
+			Want:    []string{"--mesh-name=osm", "replicas: 1", `permissive_traffic_policy_mode: "false"`},
+			NotWant: []string{"PodDisruptionBudget"},
+		},
+		{
+			Name:    "options",
+			Options: Options{MeshName: "mesh-a", PermissiveTrafficPolicy: true, Egress: true},
+			Want:    []string{"--mesh-name=mesh-a", `permissive_traffic_policy_mode: "true"`, `egress: "true"`},
+		},
+		{
+			Name: "ha",
+			IsHa: true,
+			Want: []string{"replicas: 3", "PodDisruptionBudget"},
+		},
+		{
+			Name:    "overrides win over options",
+			Options: &Options{MeshName: "mesh-a"},
+			Set:     []string{"osm.meshName=mesh-b"},
+			Want:    []string{"--mesh-name=mesh-b"},
+		},
+		{Name: "invalid options", Options: "osm", Err: true},
+	})
+}
+
+func TestOsmValues(t *testing.T) {
+	vals, err := optionValues(&transformer.Request{Options: Options{MeshName: "mesh-a", PermissiveTrafficPolicy: true, Egress: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transformertest.CheckValues(t, "testdata/osm", vals)
+	transformertest.CheckValues(t, "testdata/osm", haValues())
+}
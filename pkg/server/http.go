@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Aisuko/meshinfra/pkg/api"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// httpStatus are the HTTP status codes of the gRPC codes, the others are
+// internal server errors
+var httpStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.FailedPrecondition: http.StatusPreconditionFailed,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+}
+
+// maxRequestSize is the largest JSON body of a request, the default largest
+// message the gRPC server receives
+const maxRequestSize = 4 << 20
+
+// Handler returns the HTTP/JSON API of the server, the methods take and
+// return the JSON encoding of the messages of the gRPC API:
+//
+//	GET  /v1/meshes          ListMeshes
+//	POST /v1/render          Render
+//	POST /v1/validate        Validate
+//	POST /v1/chart-versions  ListChartVersions
+//
+// The Authorization header is passed on like the authorization metadata of a
+// gRPC call. A failure is returned as the JSON encoding of its gRPC status, a
+// body larger than 4 MB is refused.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/v1/meshes", handler(http.MethodGet, func(ctx context.Context, _ *http.Request) (proto.Message, error) {
+		return s.ListMeshes(ctx, &api.ListMeshesRequest{})
+	}))
+	mux.Handle("/v1/render", handler(http.MethodPost, func(ctx context.Context, r *http.Request) (proto.Message, error) {
+		in := &api.RenderRequest{}
+		if err := decode(r, in); err != nil {
+			return nil, err
+		}
+		return s.Render(ctx, in)
+	}))
+	mux.Handle("/v1/validate", handler(http.MethodPost, func(ctx context.Context, r *http.Request) (proto.Message, error) {
+		in := &api.RenderRequest{}
+		if err := decode(r, in); err != nil {
+			return nil, err
+		}
+		return s.Validate(ctx, in)
+	}))
+	mux.Handle("/v1/chart-versions", handler(http.MethodPost, func(ctx context.Context, r *http.Request) (proto.Message, error) {
+		in := &api.ListChartVersionsRequest{}
+		if err := decode(r, in); err != nil {
+			return nil, err
+		}
+		return s.ListChartVersions(ctx, in)
+	}))
+	return mux
+}
+
+// handler returns the handler of the method of the API, the requests of other
+// HTTP methods are refused
+func handler(method string, call func(ctx context.Context, r *http.Request) (proto.Message, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeStatus(w, status.New(codes.Unimplemented, r.Method+" is not allowed"), http.StatusMethodNotAllowed)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+		ctx := r.Context()
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", auth))
+		}
+		out, err := call(ctx, r)
+		if err != nil {
+			st := status.Convert(err)
+			code, ok := httpStatus[st.Code()]
+			if !ok {
+				code = http.StatusInternalServerError
+			}
+			writeStatus(w, st, code)
+			return
+		}
+		write(w, out, http.StatusOK)
+	})
+}
+
+// decode decodes the JSON body of the request into the message, the unknown
+// fields are an error
+func decode(r *http.Request, in proto.Message) error {
+	u := jsonpb.Unmarshaler{}
+	if err := u.Unmarshal(r.Body, in); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed parsing the request: %s", err)
+	}
+	return nil
+}
+
+func writeStatus(w http.ResponseWriter, st *status.Status, code int) {
+	write(w, st.Proto(), code)
+}
+
+// write writes the JSON encoding of the message with the status code
+func write(w http.ResponseWriter, out proto.Message, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	m := jsonpb.Marshaler{}
+	// The status code is sent, a failed write has no one left to tell
+	_ = m.Marshal(w, out)
+}
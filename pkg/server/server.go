@@ -0,0 +1,381 @@
+// Package server serves the transformers over gRPC and HTTP/JSON, so a single
+// meshinfra sidecar renders the charts of several Meshery adapters:
+//
+//	srv := server.New(server.Options{Root: "/var/lib/meshinfra"})
+//	g := grpc.NewServer()
+//	api.RegisterMeshInfraServer(g, srv)
+//
+// Every request runs with the chart repos, the registry config and the cache
+// of its tenant. The tenants are only authenticated with Options.Tenants, a
+// request sends the token of its tenant as a bearer token in the
+// authorization metadata then, without them any client can name any tenant.
+// Either way the cache only serves a chart to the requests with the
+// credentials it was downloaded with. The server reads no local chart or
+// values file on behalf of a request, the charts and the values are sent
+// with it.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Aisuko/meshinfra/pkg/api"
+	"github.com/Aisuko/meshinfra/pkg/log"
+	"github.com/Aisuko/meshinfra/pkg/meshes"
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultTenant is the tenant of the requests which name none
+const DefaultTenant = "default"
+
+// DefaultTimeout bounds every request when the options do not tell
+const DefaultTimeout = 5 * time.Minute
+
+// tenantName is the form of the tenant names, they name the directory of the
+// tenant under the root
+var tenantName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9_.]{0,61}[a-z0-9])?$`)
+
+// Options are the options of the server
+type Options struct {
+	// Root is the directory of the helm settings of the tenants, every tenant
+	// has its repositories file, registry config and cache in a directory of
+	// its name. Default is the meshinfra cache directory of the user.
+	Root string
+	// Tenants are the tokens of the tenants by name. When set, the requests
+	// have to send the token of their tenant and the other tenants are
+	// refused, otherwise the tenants are not authenticated.
+	Tenants map[string]string
+	// IndexTTL is the index TTL of the settings of the tenants
+	IndexTTL time.Duration
+	// Timeout bounds every request, default is DefaultTimeout
+	Timeout time.Duration
+	// Logger receives the log of the requests, nothing is logged when it is nil
+	Logger log.Logger
+}
+
+// Server is the MeshInfra service rendering the charts of the registered meshes
+type Server struct {
+	opts Options
+}
+
+var _ api.MeshInfraServer = (*Server)(nil)
+
+// New returns the server with the options
+func New(opts Options) *Server {
+	if opts.Root == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			cacheDir = os.TempDir()
+		}
+		opts.Root = filepath.Join(cacheDir, "meshinfra", "tenants")
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.Nop()
+	}
+	return &Server{opts: opts}
+}
+
+// Render renders the chart of a mesh
+func (s *Server) Render(ctx context.Context, r *api.RenderRequest) (*api.RenderResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	req, err := s.renderRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	result, err := transformer.Transform(ctx, r.Mesh, req)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	resp := &api.RenderResponse{
+		Manifest: result.Manifest,
+		Objects:  objects(result.Objects, true),
+		Version:  result.Version,
+		Profiles: result.Profiles,
+	}
+	if result.Lock != nil {
+		for _, dep := range result.Lock.Dependencies {
+			resp.Dependencies = append(resp.Dependencies, &api.Dependency{Name: dep.Name, Version: dep.Version, Repository: dep.Repository})
+		}
+	}
+	if result.Output != nil {
+		out, err := yaml.Marshal(result.Output)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed encoding the %s output: %s", r.Mesh, err)
+		}
+		resp.Output = string(out)
+	}
+	return resp, nil
+}
+
+// ListMeshes lists the meshes the server renders
+func (s *Server) ListMeshes(context.Context, *api.ListMeshesRequest) (*api.ListMeshesResponse, error) {
+	return &api.ListMeshesResponse{Meshes: transformer.Meshes()}, nil
+}
+
+// ListChartVersions lists the versions of a chart in its chart repo or registry
+func (s *Server) ListChartVersions(ctx context.Context, r *api.ListChartVersionsRequest) (*api.ListChartVersionsResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	req, err := s.newRequest(ctx, r.Tenant, r.Mesh)
+	if err != nil {
+		return nil, err
+	}
+	if err := chartRequest(req, r.Mesh, r.Chart); err != nil {
+		return nil, err
+	}
+	versions, err := transformer.ListVersions(ctx, req)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return &api.ListChartVersionsResponse{Versions: versions}, nil
+}
+
+// Validate renders the chart of a mesh and reports whether it succeeds, a
+// request which cannot be rendered is not an error of the call
+func (s *Server) Validate(ctx context.Context, r *api.RenderRequest) (*api.ValidateResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	req, err := s.renderRequest(ctx, r)
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			return &api.ValidateResponse{Error: status.Convert(err).Message()}, nil
+		}
+		return nil, err
+	}
+	result, err := transformer.Transform(ctx, r.Mesh, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, statusError(ctx, err)
+		}
+		return &api.ValidateResponse{Error: err.Error()}, nil
+	}
+	return &api.ValidateResponse{Valid: true, Objects: objects(result.Objects, false)}, nil
+}
+
+// newRequest returns the request of the mesh with the settings and the logger
+// of the tenant, the tenant has to be authenticated and the mesh registered
+func (s *Server) newRequest(ctx context.Context, tenant, mesh string) (*transformer.Request, error) {
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+	if !tenantName.MatchString(tenant) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid tenant name %q", tenant)
+	}
+	if err := s.authenticate(ctx, tenant); err != nil {
+		return nil, err
+	}
+	if _, err := transformer.Get(mesh); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	dir := filepath.Join(s.opts.Root, tenant)
+	return &transformer.Request{
+		Settings: transformer.Settings{
+			RepositoryConfig: filepath.Join(dir, "repositories.yaml"),
+			RepositoryCache:  filepath.Join(dir, "repository"),
+			RegistryConfig:   filepath.Join(dir, "registry.json"),
+			IndexTTL:         s.opts.IndexTTL,
+		},
+		Logger: s.opts.Logger.WithFields(log.Fields{log.FieldTenant: tenant}),
+	}, nil
+}
+
+// authenticate checks the token of the request against the one of the tenant,
+// every tenant is trusted when the server has no tokens
+func (s *Server) authenticate(ctx context.Context, tenant string) error {
+	if len(s.opts.Tenants) == 0 {
+		return nil
+	}
+	want, ok := s.opts.Tenants[tenant]
+	if !ok {
+		return status.Errorf(codes.PermissionDenied, "unknown tenant %q", tenant)
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, auth := range md.Get("authorization") {
+			if strings.HasPrefix(auth, "Bearer ") {
+				token = strings.TrimPrefix(auth, "Bearer ")
+				break
+			}
+		}
+	}
+	if token == "" {
+		return status.Errorf(codes.Unauthenticated, "the request of the tenant %q has no bearer token", tenant)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(want)) != 1 {
+		return status.Errorf(codes.Unauthenticated, "invalid token of the tenant %q", tenant)
+	}
+	return nil
+}
+
+// renderRequest returns the transformer request of the render request
+func (s *Server) renderRequest(ctx context.Context, r *api.RenderRequest) (*transformer.Request, error) {
+	req, err := s.newRequest(ctx, r.Tenant, r.Mesh)
+	if err != nil {
+		return nil, err
+	}
+	if err := chartRequest(req, r.Mesh, r.Chart); err != nil {
+		return nil, err
+	}
+
+	req.ReleaseName = r.ReleaseName
+	if req.ReleaseName == "" {
+		req.ReleaseName = r.Mesh
+	}
+	req.Namespace = r.Namespace
+	req.IsHa = r.Ha
+	req.Profiles = r.Profiles
+
+	if v := r.Values; v != nil {
+		if v.Yaml != "" {
+			if err := yaml.Unmarshal([]byte(v.Yaml), &req.Values.Raw); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "failed parsing the values: %s", err)
+			}
+		}
+		req.Values.Set = v.Set
+		req.Values.SetString = v.SetString
+		req.Values.SetFileData = v.SetFile
+	}
+
+	if r.Options != "" {
+		if req.Options, err = meshes.DecodeOptions(r.Mesh, []byte(r.Options)); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if f, ok := req.Options.(interface{ Files() []string }); ok && len(f.Files()) != 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "the options read the files %v, the server reads no files of a request", f.Files())
+		}
+	}
+	req.PostRender.Kustomization = kustomization(r.Kustomization)
+	return req, nil
+}
+
+// kustomization returns the kustomization of the API kustomization, it is nil
+// when there is none
+func kustomization(k *api.Kustomization) *transformer.Kustomization {
+	if k == nil {
+		return nil
+	}
+	out := &transformer.Kustomization{
+		Namespace:             k.Namespace,
+		CommonLabels:          k.CommonLabels,
+		CommonAnnotations:     k.CommonAnnotations,
+		PatchesStrategicMerge: k.PatchesStrategicMerge,
+	}
+	for _, img := range k.Images {
+		out.Images = append(out.Images, transformer.Image{Name: img.Name, NewName: img.NewName, NewTag: img.NewTag, Digest: img.Digest})
+	}
+	for _, p := range k.PatchesJson6902 {
+		jp := transformer.JSONPatch{Patch: p.Patch}
+		if t := p.Target; t != nil {
+			jp.Target = transformer.PatchTarget{Group: t.Group, Version: t.Version, Kind: t.Kind, Namespace: t.Namespace, Name: t.Name}
+		}
+		out.PatchesJSON6902 = append(out.PatchesJSON6902, jp)
+	}
+	return out
+}
+
+// chartRequest sets the chart of the request, the chart repo is named after the
+// mesh unless the chart names it
+func chartRequest(req *transformer.Request, mesh string, c *api.Chart) error {
+	if c == nil {
+		return status.Error(codes.InvalidArgument, "the request has no chart")
+	}
+	req.ChartName = c.Name
+	req.ChartRepoAddress = c.RepoAddress
+	req.RepoName = c.RepoName
+	if req.RepoName == "" {
+		req.RepoName = mesh
+	}
+	req.Version = c.Version
+	req.Devel = c.Devel
+	req.Verify.CosignKey = c.CosignKey
+	req.Verify.Required = c.VerifyRequired
+
+	if len(c.Archive) != 0 {
+		ch, err := loader.LoadArchive(bytes.NewReader(c.Archive))
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed loading the chart archive: %s", err)
+		}
+		req.Chart = ch
+	} else if c.Name == "" || c.RepoAddress == "" {
+		return status.Error(codes.InvalidArgument, "the chart has neither an archive nor a name and a repo address")
+	}
+
+	if cr := c.Credentials; cr != nil {
+		req.Repo = transformer.RepoOptions{
+			Username:              cr.Username,
+			Password:              cr.Password,
+			BearerToken:           cr.BearerToken,
+			PassCredentialsAll:    cr.PassCredentialsAll,
+			CAData:                cr.CaData,
+			CertData:              cr.CertData,
+			KeyData:               cr.KeyData,
+			InsecureSkipTLSVerify: cr.InsecureSkipTlsVerify,
+		}
+		req.Registry = transformer.RegistryOptions{
+			Username:  cr.Username,
+			Password:  cr.Password,
+			PlainHTTP: cr.PlainHttp,
+		}
+	}
+	return nil
+}
+
+// objects returns the API objects of the rendered objects, with their YAML
+// when raw is set
+func objects(objs []transformer.Object, raw bool) []*api.Object {
+	out := make([]*api.Object, 0, len(objs))
+	for _, obj := range objs {
+		o := &api.Object{
+			ApiVersion: obj.APIVersion,
+			Kind:       obj.Kind,
+			Namespace:  obj.Namespace,
+			Name:       obj.Name,
+			Source:     obj.Source,
+		}
+		if raw {
+			o.Raw = obj.Raw
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+// statusError returns the status of the failed transform, the one of the
+// context when it is done
+func statusError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Err()
+	}
+	for cause := err; cause != nil; {
+		if _, ok := cause.(*transformer.VerifyError); ok {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+		causer, ok := cause.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		cause = causer.Cause()
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
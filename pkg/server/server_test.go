@@ -0,0 +1,427 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/api"
+	"github.com/Aisuko/meshinfra/pkg/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const chartPath = "../consul/testdata/consul"
+
+// chartRepo serves a chart repo of the versions of the test chart
+func chartRepo(t *testing.T, dir string, versions ...string) *httptest.Server {
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range versions {
+		ch.Metadata.Version = version
+		if _, err := chartutil.Save(ch, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index, err := repo.IndexDirectory(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.WriteFile(filepath.Join(dir, "index.yaml"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.FileServer(http.Dir(dir)))
+}
+
+// chartArchive returns the packaged test chart
+func chartArchive(t *testing.T) []byte {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := chartutil.Save(ch, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// serve serves the server over an in-memory connection and returns its client
+func serve(t *testing.T, srv *server.Server) (api.MeshInfraClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	api.RegisterMeshInfraServer(g, srv)
+	go func() {
+		_ = g.Serve(lis)
+	}()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api.NewMeshInfraClient(conn), func() {
+		conn.Close()
+		g.Stop()
+	}
+}
+
+func TestServerListMeshes(t *testing.T) {
+	client, stop := serve(t, server.New(server.Options{}))
+	defer stop()
+
+	resp, err := client.ListMeshes(context.Background(), &api.ListMeshesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"consul", "istio", "kuma", "linkerd", "osm", "traefik-mesh"}; !reflect.DeepEqual(resp.Meshes, want) {
+		t.Errorf("ListMeshes returned %v, want %v", resp.Meshes, want)
+	}
+}
+
+func TestServerRender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	client, stop := serve(t, server.New(server.Options{Root: filepath.Join(dir, "tenants")}))
+	defer stop()
+
+	resp, err := client.Render(context.Background(), &api.RenderRequest{
+		Mesh:      "consul",
+		Chart:     &api.Chart{Archive: chartArchive(t)},
+		Namespace: "consul",
+		Ha:        true,
+		Values: &api.Values{
+			Yaml: "global:\n  datacenter: dc2\n",
+			Set:  []string{"server.replicas=5"},
+		},
+		Kustomization: &api.Kustomization{
+			CommonLabels: map[string]string{"team": "mesh"},
+			Images:       []*api.Image{{Name: "consul", NewTag: "1.8.0"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"namespace: consul", "replicas: 5", "-datacenter=dc2", "topologyKey: kubernetes.io/hostname", "team: mesh", "image: consul:1.8.0"} {
+		if !strings.Contains(resp.Manifest, want) {
+			t.Errorf("manifest does not contain %q:\n%s", want, resp.Manifest)
+		}
+	}
+	if resp.Version != "0.19.0" {
+		t.Errorf("Render rendered the version %s", resp.Version)
+	}
+	if len(resp.Objects) != 1 || resp.Objects[0].Kind != "StatefulSet" || resp.Objects[0].Raw == "" {
+		t.Errorf("Render returned the objects %v", resp.Objects)
+	}
+}
+
+func TestServerTenants(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The tenants name their chart repos alike, the repos differ
+	repoA := chartRepo(t, filepath.Join(dir, "a"), "1.0.0")
+	defer repoA.Close()
+	repoB := chartRepo(t, filepath.Join(dir, "b"), "1.0.0", "2.0.0")
+	defer repoB.Close()
+
+	root := filepath.Join(dir, "tenants")
+	client, stop := serve(t, server.New(server.Options{Root: root}))
+	defer stop()
+
+	for tenant, tt := range map[string]struct {
+		repo string
+		want []string
+	}{
+		"adapter-a": {repoA.URL, []string{"1.0.0"}},
+		"adapter-b": {repoB.URL, []string{"2.0.0", "1.0.0"}},
+	} {
+		chart := &api.Chart{Name: "consul", RepoAddress: tt.repo}
+		versions, err := client.ListChartVersions(context.Background(), &api.ListChartVersionsRequest{Tenant: tenant, Mesh: "consul", Chart: chart})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(versions.Versions, tt.want) {
+			t.Errorf("ListChartVersions of %s returned %v, want %v", tenant, versions.Versions, tt.want)
+		}
+
+		resp, err := client.Render(context.Background(), &api.RenderRequest{Tenant: tenant, Mesh: "consul", Chart: chart})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Version != tt.want[0] {
+			t.Errorf("Render of %s rendered the version %s, want %s", tenant, resp.Version, tt.want[0])
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(root, tenant, "repositories.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), tt.repo) {
+			t.Errorf("the repositories file of %s does not hold its repo:\n%s", tenant, data)
+		}
+	}
+}
+
+func TestServerTenantPrivateChart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	charts := filepath.Join(dir, "charts")
+	chartRepo(t, charts, "1.0.0").Close()
+	files := http.FileServer(http.Dir(charts))
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, _ := r.BasicAuth(); username != "mesh" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer private.Close()
+
+	client, stop := serve(t, server.New(server.Options{Root: filepath.Join(dir, "tenants")}))
+	defer stop()
+
+	chart := &api.Chart{Name: "consul", RepoAddress: private.URL, Credentials: &api.Credentials{Username: "mesh", Password: "secret"}}
+	if _, err := client.Render(context.Background(), &api.RenderRequest{Tenant: "adapter-a", Mesh: "consul", Chart: chart}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another client naming the tenant does not get the chart it cached
+	anonymous := &api.Chart{Name: "consul", RepoAddress: private.URL}
+	if _, err := client.Render(context.Background(), &api.RenderRequest{Tenant: "adapter-a", Mesh: "consul", Chart: anonymous}); err == nil {
+		t.Error("Render without the credentials of the private repo should fail")
+	}
+	if _, err := client.ListChartVersions(context.Background(), &api.ListChartVersionsRequest{Tenant: "adapter-a", Mesh: "consul", Chart: anonymous}); err == nil {
+		t.Error("ListChartVersions without the credentials of the private repo should fail")
+	}
+}
+
+func TestServerValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	client, stop := serve(t, server.New(server.Options{Root: dir}))
+	defer stop()
+
+	archive := chartArchive(t)
+	tests := []struct {
+		name  string
+		req   *api.RenderRequest
+		valid bool
+		code  codes.Code
+		err   string
+	}{
+		{name: "valid", req: &api.RenderRequest{Mesh: "consul", Chart: &api.Chart{Archive: archive}}, valid: true},
+		{name: "invalid values", req: &api.RenderRequest{Mesh: "consul", Chart: &api.Chart{Archive: archive}, Values: &api.Values{Set: []string{"server="}}}},
+		{name: "missing profile", req: &api.RenderRequest{Mesh: "consul", Chart: &api.Chart{Archive: archive}, Profiles: []string{"unknown"}}},
+		{name: "unknown option", req: &api.RenderRequest{Mesh: "istio", Chart: &api.Chart{Archive: archive}, Options: "unknown: true"}},
+		{name: "option files", req: &api.RenderRequest{Mesh: "istio", Chart: &api.Chart{Archive: archive}, Options: "istiod: {values: {valueFiles: [/etc/passwd]}}"}, err: "reads no files"},
+		{name: "no chart", req: &api.RenderRequest{Mesh: "consul"}},
+		{name: "invalid tenant", req: &api.RenderRequest{Tenant: "../other", Mesh: "consul", Chart: &api.Chart{Archive: archive}}},
+		{name: "unknown mesh", req: &api.RenderRequest{Mesh: "unknown", Chart: &api.Chart{Archive: archive}}, code: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Validate(context.Background(), tt.req)
+			if tt.code != codes.OK {
+				if status.Code(err) != tt.code {
+					t.Fatalf("Validate failed with %v, want the code %s", err, tt.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Valid != tt.valid || (resp.Error == "") != tt.valid {
+				t.Errorf("Validate returned %v, want valid %t", resp, tt.valid)
+			}
+			if !strings.Contains(resp.Error, tt.err) {
+				t.Errorf("Validate returned the error %q, want %q", resp.Error, tt.err)
+			}
+			if tt.valid && (len(resp.Objects) != 1 || resp.Objects[0].Raw != "") {
+				t.Errorf("Validate returned the objects %v", resp.Objects)
+			}
+		})
+	}
+}
+
+func TestServerHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv := httptest.NewServer(server.New(server.Options{Root: dir}).Handler())
+	defer srv.Close()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"mesh":        "consul",
+		"chart":       map[string]interface{}{"archive": chartArchive(t)},
+		"releaseName": "mesh",
+		"values":      map[string]interface{}{"set": []string{"server.replicas=5"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	large := []byte(`{"mesh":"consul","namespace":"` + strings.Repeat("a", 4<<20) + `"}`)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   []byte
+		code   int
+		want   string
+	}{
+		{name: "meshes", method: http.MethodGet, path: "/v1/meshes", code: http.StatusOK, want: `"meshes":["consul",`},
+		{name: "render", method: http.MethodPost, path: "/v1/render", body: body, code: http.StatusOK, want: `replicas: 5`},
+		{name: "validate", method: http.MethodPost, path: "/v1/validate", body: body, code: http.StatusOK, want: `"valid":true`},
+		{name: "unknown mesh", method: http.MethodPost, path: "/v1/render", body: []byte(`{"mesh":"unknown"}`), code: http.StatusNotFound, want: `unknown mesh`},
+		{name: "unknown field", method: http.MethodPost, path: "/v1/render", body: []byte(`{"unknown":true}`), code: http.StatusBadRequest},
+		{name: "wrong method", method: http.MethodGet, path: "/v1/render", code: http.StatusMethodNotAllowed},
+		{name: "large body", method: http.MethodPost, path: "/v1/validate", body: large, code: http.StatusBadRequest, want: "too large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, bytes.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			data, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.code {
+				t.Errorf("%s %s returned %d, want %d: %s", tt.method, tt.path, resp.StatusCode, tt.code, data)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("%s %s returned %s, want %q in it", tt.method, tt.path, data, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerTenantTokens(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv := server.New(server.Options{Root: dir, Tenants: map[string]string{"consul-adapter": "secret", "default": "other"}})
+	client, stop := serve(t, srv)
+	defer stop()
+
+	tests := []struct {
+		name   string
+		tenant string
+		auth   string
+		code   codes.Code
+	}{
+		{name: "token", tenant: "consul-adapter", auth: "Bearer secret", code: codes.OK},
+		{name: "default tenant", auth: "Bearer other", code: codes.OK},
+		{name: "token of another tenant", tenant: "consul-adapter", auth: "Bearer other", code: codes.Unauthenticated},
+		{name: "no token", tenant: "consul-adapter", code: codes.Unauthenticated},
+		{name: "not a bearer token", tenant: "consul-adapter", auth: "Basic secret", code: codes.Unauthenticated},
+		{name: "unknown tenant", tenant: "istio-adapter", auth: "Bearer secret", code: codes.PermissionDenied},
+	}
+
+	archive := chartArchive(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.auth != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tt.auth)
+			}
+			_, err := client.Render(ctx, &api.RenderRequest{Tenant: tt.tenant, Mesh: "consul", Chart: &api.Chart{Archive: archive}})
+			if code := status.Code(err); code != tt.code {
+				t.Errorf("Render returned %v, want the code %s", err, tt.code)
+			}
+			_, err = client.Validate(ctx, &api.RenderRequest{Tenant: tt.tenant, Mesh: "consul", Chart: &api.Chart{Archive: archive}})
+			if code := status.Code(err); code != tt.code {
+				t.Errorf("Validate returned %v, want the code %s", err, tt.code)
+			}
+		})
+	}
+
+	// The HTTP API passes the Authorization header on
+	h := httptest.NewServer(srv.Handler())
+	defer h.Close()
+	body, err := json.Marshal(map[string]interface{}{
+		"tenant": "consul-adapter",
+		"mesh":   "consul",
+		"chart":  map[string]interface{}{"archive": archive},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for auth, code := range map[string]int{"": http.StatusUnauthorized, "Bearer other": http.StatusUnauthorized, "Bearer secret": http.StatusOK} {
+		req, err := http.NewRequest(http.MethodPost, h.URL+"/v1/render", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Errorf("the request with the authorization %q returned %d, want %d", auth, resp.StatusCode, code)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package traefikmesh
+
+import (
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/pkg/errors"
+)
+
+// Name is the mesh name the traefik mesh transformer is registered with
+const Name = "traefik-mesh"
+
+// The traffic modes of the services without a traffic-type annotation
+const (
+	ModeHTTP = "http"
+	ModeTCP  = "tcp"
+)
+
+// Options are the traefik mesh specific options of a request, the zero values
+// keep the defaults of the chart
+type Options struct {
+	// DefaultMode is the traffic mode of the services, ModeHTTP or ModeTCP
+	DefaultMode string
+	// ACL enables the SMI access control, all the traffic is denied unless a
+	// traffic target allows it
+	ACL bool
+	// ClusterDomain is the domain of the kubernetes cluster
+	ClusterDomain string
+}
+
+// The chart runs a single controller and the proxies on every node, it has no
+// HA setup
+func init() {
+	transformer.Register(Name, &transformer.Helm{Values: transformer.MeshValues(Name, nil, optionValues)})
+}
+
+// optionValues returns the values of the traefik mesh options of the request
+func optionValues(req *transformer.Request) (map[string]interface{}, error) {
+	opts, err := options(req)
+	if err != nil {
+		return nil, err
+	}
+
+	optVals := map[string]interface{}{}
+	if opts.DefaultMode != "" {
+		optVals["mesh"] = map[string]interface{}{"defaultMode": opts.DefaultMode}
+	}
+	if opts.ACL {
+		optVals["acl"] = true
+	}
+	if opts.ClusterDomain != "" {
+		optVals["clusterDomain"] = opts.ClusterDomain
+	}
+	return optVals, nil
+}
+
+// options returns the traefik mesh options of the request
+func options(req *transformer.Request) (*Options, error) {
+	opts := &Options{}
+	if err := transformer.MeshOptions(req, Name, opts); err != nil {
+		return nil, err
+	}
+
+	switch opts.DefaultMode {
+	case "", ModeHTTP, ModeTCP:
+	default:
+		return nil, errors.Errorf("unknown traefik mesh traffic mode %q", opts.DefaultMode)
+	}
+	return opts, nil
+}
@@ -0,0 +1,39 @@
+package traefikmesh
+
+import (
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/Aisuko/meshinfra/pkg/transformer/transformertest"
+)
+
+func TestTransformTraefikMesh(t *testing.T) {
+	transformertest.Run(t, Name, transformer.Request{ReleaseName: "traefik-mesh", Namespace: "traefik-mesh", ChartPath: "testdata/traefik-mesh"}, []transformertest.Case{
+		{
+			Name:    "default",
+			Want:    []string{"replicas: 1", "--defaultMode=http", "--clusterDomain=cluster.local"},
+			NotWant: []string{"--acl"},
+		},
+		{
+			Name:    "options",
+			Options: Options{DefaultMode: ModeTCP, ACL: true, ClusterDomain: "mesh.local"},
+			Want:    []string{"--defaultMode=tcp", "--acl", "--clusterDomain=mesh.local"},
+		},
+		{
+			Name:    "overrides win over options",
+			Options: &Options{DefaultMode: ModeTCP},
+			Set:     []string{"mesh.defaultMode=http"},
+			Want:    []string{"--defaultMode=http"},
+		},
+		{Name: "ha", IsHa: true, Err: true},
+		{Name: "unknown traffic mode", Options: Options{DefaultMode: "udp"}, Err: true},
+	})
+}
+
+func TestTraefikMeshValues(t *testing.T) {
+	vals, err := optionValues(&transformer.Request{Options: Options{DefaultMode: ModeTCP, ACL: true, ClusterDomain: "mesh.local"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transformertest.CheckValues(t, "testdata/traefik-mesh", vals)
+}
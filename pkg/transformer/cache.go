@@ -0,0 +1,260 @@
+package transformer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// DefaultIndexTTL is how long a downloaded repo index is used before it is
+// refreshed when the settings do not tell
+const DefaultIndexTTL = 5 * time.Minute
+
+// The cache lives in the repository cache of the settings. The indexes are
+// keyed by the repo URL, the chart archives by their sha256 digest, which the
+// index entry of the chart name and version gives. Both are kept apart by the
+// credentials they were downloaded with, see credentialKey.
+const (
+	indexCacheDir = "index"
+	chartCacheDir = "charts/sha256"
+)
+
+// Prewarm fills the cache with the repo indexes and the chart archives of the
+// requests, the transforms of the same charts work offline afterwards. The
+// requests of local or in-memory charts are skipped.
+func Prewarm(ctx context.Context, reqs ...*Request) error {
+	ctx, done := withHTTPClients(ctx)
+	defer done()
+
+	for _, req := range reqs {
+		if req.Chart != nil || req.ChartPath != "" {
+			continue
+		}
+		if _, err := locateChart(ctx, envSettings(req), req); err != nil {
+			return errors.Wrapf(err, "failed prewarming the cache with the %s chart", req.ChartName)
+		}
+	}
+	return nil
+}
+
+// indexTTL returns the index TTL of the request, it is negative when the
+// indexes are always refreshed
+func indexTTL(req *Request) time.Duration {
+	if req.Settings.IndexTTL == 0 {
+		return DefaultIndexTTL
+	}
+	return req.Settings.IndexTTL
+}
+
+// cacheKey returns the key of the repo URL in the cache
+func cacheKey(repoURL string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSuffix(repoURL, "/")))
+	return hex.EncodeToString(sum[:])
+}
+
+// credentialKey returns the key of the credentials of the options in the
+// cache. The requests only share the indexes and the charts cached with the
+// same credentials and the same verification of the repo, an anonymous request
+// never reads the private charts an authenticated one cached and a verifying
+// one never reads the ones downloaded without verification.
+func credentialKey(opts RepoOptions) string {
+	data, _ := json.Marshal([]interface{}{
+		opts.Username, opts.Password, opts.BearerToken,
+		opts.CertFile, opts.KeyFile, opts.CertData, opts.KeyData,
+		opts.CAFile, opts.CAData, opts.InsecureSkipTLSVerify,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// indexPath returns the path of the cached index of the repo URL downloaded
+// with the options
+func indexPath(settings *cli.EnvSettings, repoURL string, opts RepoOptions) string {
+	return filepath.Join(settings.RepositoryCache, indexCacheDir, credentialKey(opts), helmpath.CacheIndexFile(cacheKey(repoURL)))
+}
+
+// archivePath returns the path of the cached chart archive with the digest
+// downloaded with the options
+func archivePath(settings *cli.EnvSettings, opts RepoOptions, digest string) string {
+	return filepath.Join(settings.RepositoryCache, filepath.FromSlash(chartCacheDir), credentialKey(opts), digest+".tgz")
+}
+
+// refreshIndex downloads the index of the repo unless the cached one is fresh.
+// A failed download falls back to the cached index, however old it is, unless
+// the repo refused the credentials.
+func refreshIndex(ctx context.Context, settings *cli.EnvSettings, req *Request, entry *repoEntry) error {
+	logger := requestLogger(req)
+	opts := repoOptions(req, entry)
+	path := indexPath(settings, entry.URL, opts)
+
+	fi, statErr := os.Stat(path)
+	if statErr == nil {
+		if ttl := indexTTL(req); ttl > 0 && time.Since(fi.ModTime()) < ttl {
+			logger.Debugf("The cached %s repo index is fresh", entry.Name)
+			return nil
+		}
+	}
+
+	if err := downloadIndex(ctx, settings, entry, opts, path); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if statErr == nil && !isRefused(err) {
+			logger.Warnf("Refresh the %s repo index failed, the cached index is used: %s", entry.Name, err)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// downloadIndex downloads the index of the repo to the path, the index is
+// replaced at once so that concurrent transforms never read a partial one
+func downloadIndex(ctx context.Context, settings *cli.EnvSettings, entry *repoEntry, opts RepoOptions, path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(dir, "download")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	keyed := *entry
+	keyed.Name = cacheKey(entry.URL)
+	r, err := newChartRepository(ctx, settings, &keyed.Entry, opts)
+	if err != nil {
+		return err
+	}
+	r.CachePath = tmp
+
+	fname, err := r.DownloadIndexFile()
+	if err != nil {
+		return err
+	}
+	return os.Rename(fname, path)
+}
+
+// loadIndex returns the cached index of the repo URL downloaded with the options
+func loadIndex(settings *cli.EnvSettings, repoURL string, opts RepoOptions) (*repo.IndexFile, error) {
+	return repo.LoadIndexFile(indexPath(settings, repoURL, opts))
+}
+
+// fetchChart returns the path of the cached archive of the chart version, it
+// is downloaded unless the cache holds an archive with the digest of the index
+func fetchChart(ctx context.Context, settings *cli.EnvSettings, req *Request, entry *repoEntry, cv *repo.ChartVersion) (string, error) {
+	logger := requestLogger(req)
+	if len(cv.URLs) == 0 {
+		return "", errors.Errorf("the %s chart %s has no URL in the %s repo index", cv.Name, cv.Version, entry.Name)
+	}
+	chartURL, err := repo.ResolveReferenceURL(entry.URL, cv.URLs[0])
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid URL of the %s chart %s", cv.Name, cv.Version)
+	}
+	opts := repoOptions(req, entry)
+
+	digest := strings.TrimPrefix(cv.Digest, "sha256:")
+	if digest == "" {
+		logger.Warnf("The %s chart %s has no digest in the index, it is not verified", cv.Name, cv.Version)
+	} else {
+		path := archivePath(settings, opts, digest)
+		if sum, err := fileDigest(path); err == nil && sum == digest {
+			logger.Debugf("Use the cached %s chart %s", cv.Name, cv.Version)
+			if err := verifyRepoChart(ctx, settings, req, entry, chartURL, path); err != nil {
+				return "", err
+			}
+			return path, nil
+		}
+	}
+
+	data, err := downloadFile(ctx, settings, entry, opts, chartURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed downloading the %s chart %s", cv.Name, cv.Version)
+	}
+
+	sum := sha256.Sum256(data.Bytes())
+	got := hex.EncodeToString(sum[:])
+	if digest != "" && got != digest {
+		return "", errors.Errorf("the digest of the %s chart %s is sha256:%s, the index expects sha256:%s", cv.Name, cv.Version, got, digest)
+	}
+
+	path := archivePath(settings, opts, got)
+	if err := writeFile(path, data.Bytes()); err != nil {
+		return "", err
+	}
+	logger.Debugf("Cached the %s chart %s", cv.Name, cv.Version)
+	if err := verifyRepoChart(ctx, settings, req, entry, chartURL, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// downloadFile downloads the file with the getter of its URL scheme and the
+// options of the repo
+func downloadFile(ctx context.Context, settings *cli.EnvSettings, entry *repoEntry, opts RepoOptions, fileURL string) (*bytes.Buffer, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return nil, err
+	}
+	g, err := repoProviders(ctx, settings, entry.URL, opts).ByScheme(u.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	return g.Get(fileURL,
+		getter.WithURL(entry.URL),
+		getter.WithTLSClientConfig(entry.CertFile, entry.KeyFile, entry.CAFile),
+		getter.WithBasicAuth(opts.Username, opts.Password),
+	)
+}
+
+// fileDigest returns the hex sha256 digest of the file
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeFile writes the file at once, a concurrent reader never sees part of it
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "download")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
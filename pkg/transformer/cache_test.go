@@ -0,0 +1,288 @@
+package transformer_test
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// countingRepo serves the chart repo of the directory and counts the requests
+// of every path
+type countingRepo struct {
+	*httptest.Server
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newCountingRepo(dir string) *countingRepo {
+	r := &countingRepo{counts: map[string]int{}}
+	files := http.FileServer(http.Dir(dir))
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		r.counts[req.URL.Path]++
+		r.mu.Unlock()
+		files.ServeHTTP(w, req)
+	}))
+	return r
+}
+
+func (r *countingRepo) count(path string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counts[path]
+}
+
+// cacheTest returns the directory of the chart repo and the settings of a
+// test, they are removed by the returned func
+func cacheTest(t *testing.T) (string, transformer.Settings, func()) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	charts := filepath.Join(dir, "charts")
+	writeChartRepo(t, charts, "1.0.0")
+
+	settings := transformer.Settings{
+		RepositoryConfig: filepath.Join(dir, "helm", "repositories.yaml"),
+		RepositoryCache:  filepath.Join(dir, "helm", "repository"),
+	}
+	return charts, settings, func() { _ = os.RemoveAll(dir) }
+}
+
+func repoRequest(address string, settings transformer.Settings) *transformer.Request {
+	return &transformer.Request{
+		ChartName:        "mesh",
+		ReleaseName:      "mesh",
+		RepoName:         "local",
+		ChartRepoAddress: address,
+		Settings:         settings,
+	}
+}
+
+func TestHelmTransformCache(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	srv := newCountingRepo(charts)
+	defer srv.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := (&transformer.Helm{}).Transform(context.Background(), repoRequest(srv.URL, settings)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := srv.count("/index.yaml"); n != 1 {
+		t.Errorf("the index is downloaded %d times, want once within the TTL", n)
+	}
+	if n := srv.count("/mesh-1.0.0.tgz"); n != 1 {
+		t.Errorf("the chart is downloaded %d times, want once", n)
+	}
+
+	// Without TTL the index is always refreshed, the chart is still cached
+	settings.IndexTTL = -1
+	if _, err := (&transformer.Helm{}).Transform(context.Background(), repoRequest(srv.URL, settings)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.count("/index.yaml"); n != 2 {
+		t.Errorf("the index is downloaded %d times, want twice", n)
+	}
+	if n := srv.count("/mesh-1.0.0.tgz"); n != 1 {
+		t.Errorf("the chart is downloaded %d times, want once", n)
+	}
+}
+
+func TestPrewarmOffline(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	srv := newCountingRepo(charts)
+	if err := transformer.Prewarm(context.Background(), repoRequest(srv.URL, settings)); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	settings.IndexTTL = -1
+	result, err := (&transformer.Helm{}).Transform(context.Background(), repoRequest(srv.URL, settings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != "1.0.0" {
+		t.Errorf("Transform rendered the version %s, want 1.0.0", result.Version)
+	}
+}
+
+func TestHelmTransformCacheDigest(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	srv := newCountingRepo(charts)
+	defer srv.Close()
+
+	if err := transformer.Prewarm(context.Background(), repoRequest(srv.URL, settings)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A corrupted archive of the cache is downloaded again
+	archives, err := filepath.Glob(filepath.Join(settings.RepositoryCache, "charts", "sha256", "*", "*.tgz"))
+	if err != nil || len(archives) != 1 {
+		t.Fatalf("the cache holds the archives %v: %v", archives, err)
+	}
+	if err := ioutil.WriteFile(archives[0], []byte("corrupted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&transformer.Helm{}).Transform(context.Background(), repoRequest(srv.URL, settings)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.count("/mesh-1.0.0.tgz"); n != 2 {
+		t.Errorf("the chart is downloaded %d times, want twice", n)
+	}
+
+	// An archive of the repo which does not match the index is rejected
+	if err := os.Remove(archives[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(charts, "mesh-1.0.0.tgz"), []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = (&transformer.Helm{}).Transform(context.Background(), repoRequest(srv.URL, settings))
+	if err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Fatalf("Transform of a tampered chart returned %v, want a digest error", err)
+	}
+}
+
+func TestHelmTransformUnrelatedRepos(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	srv := newCountingRepo(charts)
+	defer srv.Close()
+
+	f := repo.NewFile()
+	f.Update(&repo.Entry{Name: "broken", URL: srv.URL + "/broken"})
+	if err := os.MkdirAll(filepath.Dir(settings.RepositoryConfig), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.WriteFile(settings.RepositoryConfig, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := (&transformer.Helm{}).Transform(context.Background(), repoRequest(srv.URL, settings)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.count("/broken/index.yaml"); n != 0 {
+		t.Errorf("the unrelated repo is refreshed %d times", n)
+	}
+
+	req := repoRequest(srv.URL, settings)
+	req.RefreshAllRepos = true
+	if _, err := (&transformer.Helm{}).Transform(context.Background(), req); err == nil {
+		t.Error("Transform refreshing the broken repo should fail")
+	}
+
+	req.IgnoreUnrelatedRepoErrors = true
+	if _, err := (&transformer.Helm{}).Transform(context.Background(), req); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHelmTransformCacheCredentials(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	files := http.FileServer(http.Dir(charts))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, _ := r.BasicAuth(); username != "mesh" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	request := func(password string, ttl time.Duration) *transformer.Request {
+		s := settings
+		s.IndexTTL = ttl
+		req := repoRequest(srv.URL, s)
+		if password != "" {
+			req.Repo = transformer.RepoOptions{Username: "mesh", Password: password}
+		}
+		return req
+	}
+	if err := transformer.Prewarm(context.Background(), request("secret", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The index and the chart cached with the credentials are theirs only, a
+	// refused refresh does not fall back to them
+	tests := []struct {
+		name     string
+		password string
+		ttl      time.Duration
+		wantErr  bool
+	}{
+		{name: "cached", password: "secret"},
+		{name: "refreshed", password: "secret", ttl: -1},
+		{name: "anonymous", wantErr: true},
+		{name: "anonymous refresh", ttl: -1, wantErr: true},
+		{name: "wrong password", password: "wrong", wantErr: true},
+		{name: "wrong password refresh", password: "wrong", ttl: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&transformer.Helm{}).Transform(context.Background(), request(tt.password, tt.ttl))
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Transform returned %v, want an error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHelmTransformCacheVerification(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	srv := httptest.NewTLSServer(http.FileServer(http.Dir(charts)))
+	defer srv.Close()
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	insecure := repoRequest(srv.URL, settings)
+	insecure.Repo = transformer.RepoOptions{InsecureSkipTLSVerify: true}
+	if err := transformer.Prewarm(context.Background(), insecure); err != nil {
+		t.Fatal(err)
+	}
+
+	// The index and the chart downloaded without verification are never served
+	// to a request which verifies the repo
+	tests := []struct {
+		name    string
+		opts    transformer.RepoOptions
+		wantErr bool
+	}{
+		{name: "insecure", opts: transformer.RepoOptions{InsecureSkipTLSVerify: true}},
+		{name: "verified", opts: transformer.RepoOptions{CAData: caData}},
+		{name: "unknown CA", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := repoRequest(srv.URL, settings)
+			req.Repo = tt.opts
+			_, err := (&transformer.Helm{}).Transform(context.Background(), req)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Transform returned %v, want an error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
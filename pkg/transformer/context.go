@@ -0,0 +1,26 @@
+package transformer
+
+import "context"
+
+// runContext runs the step and returns as soon as the context is done. It is
+// meant for the steps which cannot take a context, like the rendering of helm
+// or a post-renderer, a step which is cut short finishes in the background and
+// its outcome is dropped. The downloads take the context instead and stop when
+// it is done.
+func runContext(ctx context.Context, step func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- step()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,215 @@
+package transformer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	common "github.com/Aisuko/meshinfra/pkg/common"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// resolveDependencies adds the dependencies of the chart which are missing in
+// its charts directory, they are taken from their repo through the cache like
+// the chart of a request so they resolve offline once cached. Nothing is
+// written to the chart, the dependencies are only added to the loaded one.
+// The dir is the directory of a chart directory, the file:// repositories are
+// relative to it. It returns the lock of the dependencies.
+func resolveDependencies(ctx context.Context, settings *cli.EnvSettings, req *Request, ch *chart.Chart, dir string) (*chart.Lock, error) {
+	deps := ch.Metadata.Dependencies
+	if len(deps) == 0 {
+		return nil, nil
+	}
+	logger := requestLogger(req)
+
+	var f *repoFile
+	lock := &chart.Lock{Generated: time.Now()}
+	for _, dep := range deps {
+		sub := dependency(ch, dep.Name)
+		if sub == nil {
+			if f == nil {
+				var err error
+				if f, err = loadRepoFile(settings.RepositoryConfig); err != nil {
+					return nil, err
+				}
+			}
+
+			path, subDir, err := locateDependency(ctx, settings, req, f, dep, dir)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed resolving the dependency %s of the chart %s", dep.Name, ch.Name())
+			}
+			if sub, err = loader.Load(path); err != nil {
+				return nil, errors.Wrapf(err, "failed loading the dependency %s of the chart %s", dep.Name, ch.Name())
+			}
+			if _, err := resolveDependencies(ctx, settings, req, sub, subDir); err != nil {
+				return nil, err
+			}
+			ch.AddDependency(sub)
+			logger.Infof("Resolved the dependency %s %s from %s", dep.Name, sub.Metadata.Version, dep.Repository)
+		}
+
+		lock.Dependencies = append(lock.Dependencies, &chart.Dependency{
+			Name:       dep.Name,
+			Version:    sub.Metadata.Version,
+			Repository: dep.Repository,
+			Alias:      dep.Alias,
+		})
+	}
+
+	digest, err := lockDigest(deps, lock.Dependencies)
+	if err != nil {
+		return nil, err
+	}
+	lock.Digest = digest
+	return lock, nil
+}
+
+// copyChart returns a copy of the chart and its subcharts, the dependencies,
+// the metadata and the values which resolving the dependencies and rendering
+// change are copied, the templates and the files are shared
+func copyChart(ch *chart.Chart) *chart.Chart {
+	c := *ch
+	if ch.Metadata != nil {
+		md := *ch.Metadata
+		if deps := ch.Metadata.Dependencies; deps != nil {
+			md.Dependencies = make([]*chart.Dependency, len(deps))
+			for i, dep := range deps {
+				d := *dep
+				md.Dependencies[i] = &d
+			}
+		}
+		c.Metadata = &md
+	}
+	if ch.Values != nil {
+		c.Values = common.MergeValues(ch.Values)
+	}
+
+	subs := ch.Dependencies()
+	c.SetDependencies()
+	for _, sub := range subs {
+		c.AddDependency(copyChart(sub))
+	}
+	return &c
+}
+
+// dependency returns the subchart of the chart by the name, nil when the chart
+// has none
+func dependency(ch *chart.Chart, name string) *chart.Chart {
+	for _, sub := range ch.Dependencies() {
+		if sub.Name() == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// locateDependency returns the local path of the dependency, and its directory
+// when it is a chart directory
+func locateDependency(ctx context.Context, settings *cli.EnvSettings, req *Request, f *repoFile, dep *chart.Dependency, dir string) (string, string, error) {
+	repository := dep.Repository
+	switch {
+	case repository == "":
+		return "", "", errors.New("it is missing in the charts directory and has no repository")
+	case strings.HasPrefix(repository, "file://"):
+		if dir == "" {
+			return "", "", errors.Errorf("the repository %s is only supported for a chart directory", repository)
+		}
+		path := filepath.Join(dir, strings.TrimPrefix(repository, "file://"))
+		fi, err := os.Stat(path)
+		if err != nil {
+			return "", "", err
+		}
+		if fi.IsDir() {
+			return path, path, nil
+		}
+		return path, "", nil
+	}
+
+	dreq := *req
+	dreq.ChartName = dep.Name
+	dreq.Version = dep.Version
+	dreq.Devel = false
+	dreq.ChartRepoAddress = repository
+	if IsOCI(repository) {
+		// The credentials of the request are the ones of its own registry only,
+		// the other registries get the ones of the registry config
+		if !sameRegistry(req.ChartRepoAddress, repository) {
+			dreq.Registry = RegistryOptions{PlainHTTP: req.Registry.PlainHTTP}
+			dreq.Repo = RepoOptions{}
+		}
+		path, err := locateOCIChart(ctx, settings, &dreq)
+		return path, "", err
+	}
+
+	entry, err := dependencyRepo(f, repository)
+	if err != nil {
+		return "", "", err
+	}
+	// The options of the request are the ones of its own repo only
+	dreq.RepoName = entry.Name
+	dreq.ChartRepoAddress = entry.URL
+	if entry.URL != req.ChartRepoAddress {
+		dreq.Repo = entry.options()
+	}
+
+	if err := refreshIndex(ctx, settings, &dreq, entry); err != nil {
+		return "", "", err
+	}
+	path, err := locateRepoChart(ctx, settings, &dreq, entry)
+	return path, "", err
+}
+
+// sameRegistry reports whether both addresses are oci:// addresses of the same
+// registry host
+func sameRegistry(a, b string) bool {
+	if !IsOCI(a) || !IsOCI(b) {
+		return false
+	}
+	refA, errA := parseOCIRef(a, "")
+	refB, errB := parseOCIRef(b, "")
+	return errA == nil && errB == nil && refA.host == refB.host
+}
+
+// dependencyRepo returns the repo entry of the repository of a dependency, a
+// repo of the repo config is named with "@name" or "alias:name", the entry of
+// a repo URL is the one of the repo config when it has one
+func dependencyRepo(f *repoFile, repository string) (*repoEntry, error) {
+	name := ""
+	switch {
+	case strings.HasPrefix(repository, "@"):
+		name = strings.TrimPrefix(repository, "@")
+	case strings.HasPrefix(repository, "alias:"):
+		name = strings.TrimPrefix(repository, "alias:")
+	}
+
+	if f != nil {
+		for _, entry := range f.Repositories {
+			if (name != "" && entry.Name == name) || (name == "" && strings.TrimSuffix(entry.URL, "/") == strings.TrimSuffix(repository, "/")) {
+				return entry, nil
+			}
+		}
+	}
+	if name != "" {
+		return nil, errors.Errorf("no repository named %q in the repo config", name)
+	}
+	return &repoEntry{Entry: repo.Entry{Name: repository, URL: repository}}, nil
+}
+
+// lockDigest returns the digest of the dependencies and their locked versions
+func lockDigest(deps, locked []*chart.Dependency) (string, error) {
+	data, err := json.Marshal([2][]*chart.Dependency{deps, locked})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
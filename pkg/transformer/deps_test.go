@@ -0,0 +1,240 @@
+package transformer_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/Aisuko/meshinfra/pkg/transformer/registrytest"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const umbrellaPath = "testdata/umbrella"
+
+func TestHelmTransformDependencies(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	writeChartRepo(t, charts, "1.1.0", "2.0.0")
+	srv := newCountingRepo(charts)
+	defer srv.Close()
+
+	reg := registrytest.New()
+	defer reg.Close()
+	pushCharts(t, reg, "1.0.0")
+
+	f := repo.NewFile()
+	f.Update(&repo.Entry{Name: "local", URL: srv.URL})
+	if err := os.MkdirAll(filepath.Dir(settings.RepositoryConfig), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.WriteFile(settings.RepositoryConfig, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		chartPath  string
+		repository string
+		version    string
+		want       string
+		wantErr    bool
+	}{
+		{name: "chart directory", chartPath: umbrellaPath, repository: "file://../mesh", want: "0.1.0"},
+		{name: "repo URL", repository: srv.URL, version: "^1.0.0", want: "1.1.0"},
+		{name: "repo name", repository: "@local", version: "~1.0.0", want: "1.0.0"},
+		{name: "repo alias", repository: "alias:local", version: "2.0.0", want: "2.0.0"},
+		{name: "registry", repository: reg.Address("meshes"), version: "1.0.0", want: "1.0.0"},
+		{name: "unknown repo name", repository: "@unknown", wantErr: true},
+		{name: "file in-memory", repository: "file://../mesh", wantErr: true},
+		{name: "no repository", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &transformer.Request{ReleaseName: "mesh", ChartPath: tt.chartPath, Settings: settings}
+			req.Registry.PlainHTTP = true
+			if tt.chartPath == "" {
+				ch, err := loader.Load(umbrellaPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ch.Metadata.Dependencies[0].Repository = tt.repository
+				ch.Metadata.Dependencies[0].Version = tt.version
+				req.Chart = ch
+				defer func() {
+					if len(ch.Dependencies()) != 0 {
+						t.Error("the dependencies are added to the chart of the request")
+					}
+				}()
+			}
+
+			result, err := (&transformer.Helm{}).Transform(context.Background(), req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Transform should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Lock == nil || len(result.Lock.Dependencies) != 1 {
+				t.Fatalf("Transform returned the lock %+v, want the mesh dependency", result.Lock)
+			}
+			dep := result.Lock.Dependencies[0]
+			if dep.Name != "mesh" || dep.Version != tt.want || dep.Repository != tt.repository {
+				t.Errorf("Transform locked %+v, want the mesh %s from %s", dep, tt.want, tt.repository)
+			}
+			if !strings.HasPrefix(result.Lock.Digest, "sha256:") {
+				t.Errorf("Transform locked with the digest %q", result.Lock.Digest)
+			}
+			if !strings.Contains(result.Manifest, "replicas: 2") || !strings.Contains(result.Manifest, "chart: mesh-"+tt.want) {
+				t.Errorf("manifest does not render the dependency:\n%s", result.Manifest)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(umbrellaPath, "charts")); !os.IsNotExist(err) {
+		t.Errorf("the dependencies are written to the chart directory: %v", err)
+	}
+}
+
+func TestHelmTransformDependenciesOffline(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	srv := newCountingRepo(charts)
+	ch, err := loader.Load(umbrellaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.Metadata.Dependencies[0].Repository = srv.URL
+	req := &transformer.Request{ReleaseName: "mesh", Chart: ch, Settings: settings}
+
+	if _, err := (&transformer.Helm{}).Transform(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	req.Settings.IndexTTL = -1
+	result, err := (&transformer.Helm{}).Transform(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Lock.Dependencies[0].Version != "1.0.0" {
+		t.Errorf("Transform locked %+v offline, want the cached 1.0.0", result.Lock.Dependencies[0])
+	}
+}
+
+func TestHelmTransformDependenciesChartUnchanged(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	srv := newCountingRepo(charts)
+	defer srv.Close()
+
+	ch, err := loader.Load(umbrellaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.Metadata.Dependencies[0].Repository = srv.URL
+	extra, err := loader.Load(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra.Metadata.Name = "extra"
+	ch.AddDependency(extra)
+
+	// The transforms of the chart of the caller resolve and render their own
+	// copies of it
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := (&transformer.Helm{}).Transform(context.Background(), &transformer.Request{ReleaseName: "mesh", Chart: ch, Settings: settings})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if deps := ch.Dependencies(); len(deps) != 1 || deps[0] != extra {
+		t.Errorf("the subcharts of the chart are changed to %v", deps)
+	}
+	if extra.Parent() != ch {
+		t.Error("the subchart of the chart is moved to another parent")
+	}
+	if ch.Metadata.Dependencies[0].Enabled {
+		t.Error("the dependencies of the chart metadata are changed")
+	}
+}
+
+func TestHelmTransformDependenciesRegistryCredentials(t *testing.T) {
+	_, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	parent := registrytest.NewWithAuth("mesh", "secret")
+	defer parent.Close()
+	other := registrytest.NewWithAuth("other", "other-secret")
+	defer other.Close()
+	pushCharts(t, parent, "1.0.0")
+	pushCharts(t, other, "1.0.0")
+
+	auth := base64.StdEncoding.EncodeToString([]byte("other:other-secret"))
+	settings.RegistryConfig = filepath.Join(filepath.Dir(settings.RepositoryConfig), "config.json")
+	if err := os.MkdirAll(filepath.Dir(settings.RegistryConfig), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(settings.RegistryConfig, []byte(`{"auths":{"`+other.Host()+`":{"auth":"`+auth+`"}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The dependency on the registry of the chart gets the credentials of the
+	// request, the one on another registry the ones of the registry config
+	for _, reg := range []*registrytest.Registry{parent, other} {
+		umbrella, err := loader.Load(umbrellaPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		umbrella.Metadata.Dependencies[0].Repository = reg.Address("meshes")
+		umbrella.Metadata.Dependencies[0].Version = "1.0.0"
+		if err := parent.Push("umbrellas", umbrella); err != nil {
+			t.Fatal(err)
+		}
+
+		s := settings
+		s.RepositoryCache = filepath.Join(settings.RepositoryCache, reg.Host())
+		req := ociRequest(parent.Address("umbrellas"), s)
+		req.ChartName = "umbrella"
+		req.Registry.Username = "mesh"
+		req.Registry.Password = "secret"
+		if _, err := (&transformer.Helm{}).Transform(context.Background(), req); err != nil {
+			t.Fatalf("Transform with the dependency on %s failed: %v", reg.Host(), err)
+		}
+	}
+
+	for _, c := range other.Credentials() {
+		if c != "other:other-secret" {
+			t.Errorf("the other registry was sent the credentials %q", c)
+		}
+	}
+	if len(other.Credentials()) == 0 {
+		t.Error("the other registry was sent no credentials")
+	}
+}
@@ -0,0 +1,273 @@
+package transformer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// RepoOptions are the credentials and the TLS settings of the chart repo of a
+// request. Only the paths of the files are written to the repo config, the
+// credentials and the in-memory PEM data are kept out of it and have to be
+// passed with every request.
+type RepoOptions struct {
+	Username string
+	Password string
+	// BearerToken is sent as the Authorization header, it wins over the
+	// username and the password
+	BearerToken string
+	// PassCredentialsAll sends the credentials to the chart URLs on other hosts
+	// than the repo, they are only sent to the repo host by default
+	PassCredentialsAll bool
+	// CAFile is the path of the PEM bundle of the CAs the repo is verified with,
+	// they are trusted in addition to the system CAs
+	CAFile string
+	// CAData is the PEM bundle of the CAs held in memory
+	CAData []byte
+	// CertFile and KeyFile are the paths of the PEM client certificate and key
+	CertFile string
+	KeyFile  string
+	// CertData and KeyData are the PEM client certificate and key held in memory
+	CertData []byte
+	KeyData  []byte
+	// InsecureSkipTLSVerify skips the verification of the repo certificate
+	InsecureSkipTLSVerify bool
+}
+
+// repoOptions returns the options of the chart repo entry, the request holds
+// the ones of its own repo and the repo config the ones of the other repos
+func repoOptions(req *Request, entry *repoEntry) RepoOptions {
+	if entry.Name == req.RepoName && entry.URL == req.ChartRepoAddress {
+		return req.Repo
+	}
+	return entry.options()
+}
+
+// entry returns the repo config entry of the repo, without the secrets
+func (o *RepoOptions) entry(name, repoURL string) *repoEntry {
+	return &repoEntry{
+		Entry: repo.Entry{
+			Name:     name,
+			URL:      repoURL,
+			CAFile:   o.CAFile,
+			CertFile: o.CertFile,
+			KeyFile:  o.KeyFile,
+		},
+		InsecureSkipTLSVerify: o.InsecureSkipTLSVerify,
+		PassCredentialsAll:    o.PassCredentialsAll,
+	}
+}
+
+// tlsConfig returns the TLS config of the options
+func (o *RepoOptions) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: o.InsecureSkipTLSVerify} // #nosec G402 only on request
+
+	if o.CAFile != "" || len(o.CAData) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		data := o.CAData
+		if o.CAFile != "" {
+			if data, err = ioutil.ReadFile(o.CAFile); err != nil {
+				return nil, errors.Wrap(err, "failed reading the CA bundle")
+			}
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.New("the CA bundle has no PEM certificate")
+		}
+		cfg.RootCAs = pool
+	}
+
+	certData, keyData := o.CertData, o.KeyData
+	if o.CertFile != "" || o.KeyFile != "" {
+		var err error
+		if certData, err = ioutil.ReadFile(o.CertFile); err != nil {
+			return nil, errors.Wrap(err, "failed reading the client certificate")
+		}
+		if keyData, err = ioutil.ReadFile(o.KeyFile); err != nil {
+			return nil, errors.Wrap(err, "failed reading the client key")
+		}
+	}
+	if len(certData) > 0 || len(keyData) > 0 {
+		cert, err := tls.X509KeyPair(certData, keyData)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid client certificate")
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// httpClient returns the http client of the TLS settings of the options. The
+// downloads of a request share the client of the same settings, see
+// withHTTPClients, a client out of a request keeps no idle connection.
+func (o *RepoOptions) httpClient(ctx context.Context) (*http.Client, error) {
+	clients, _ := ctx.Value(httpClientsKey{}).(*httpClients)
+	if clients == nil {
+		return o.newHTTPClient(true)
+	}
+
+	key := o.tlsKey()
+	clients.mu.Lock()
+	defer clients.mu.Unlock()
+	if client, ok := clients.clients[key]; ok {
+		return client, nil
+	}
+	client, err := o.newHTTPClient(false)
+	if err != nil {
+		return nil, err
+	}
+	clients.clients[key] = client
+	return client, nil
+}
+
+// newHTTPClient returns a new http client of the TLS settings of the options
+func (o *RepoOptions) newHTTPClient(disableKeepAlives bool) (*http.Client, error) {
+	cfg, err := o.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   cfg,
+			DisableKeepAlives: disableKeepAlives,
+		},
+	}, nil
+}
+
+// tlsKey returns the key of the TLS settings of the options
+func (o *RepoOptions) tlsKey() string {
+	data, _ := json.Marshal([]interface{}{
+		o.CAFile, o.CAData, o.CertFile, o.KeyFile, o.CertData, o.KeyData, o.InsecureSkipTLSVerify,
+	})
+	return string(data)
+}
+
+// httpClientsKey is the context key of the http clients of a request
+type httpClientsKey struct{}
+
+// httpClients are the http clients of a request by their TLS settings
+type httpClients struct {
+	mu      sync.Mutex
+	clients map[string]*http.Client
+}
+
+// withHTTPClients returns the context of a request whose downloads share their
+// http clients, and the func closing their idle connections once the request
+// is done. The context of a request which has them already is kept.
+func withHTTPClients(ctx context.Context) (context.Context, func()) {
+	if _, ok := ctx.Value(httpClientsKey{}).(*httpClients); ok {
+		return ctx, func() {}
+	}
+	clients := &httpClients{clients: map[string]*http.Client{}}
+	return context.WithValue(ctx, httpClientsKey{}, clients), func() {
+		clients.mu.Lock()
+		defer clients.mu.Unlock()
+		for _, client := range clients.clients {
+			client.CloseIdleConnections()
+		}
+	}
+}
+
+// repoProviders returns the getters of the chart repo, the http and https URLs
+// are downloaded with the options of the repo and given up when the context is
+// done
+func repoProviders(ctx context.Context, settings *cli.EnvSettings, repoURL string, opts RepoOptions) getter.Providers {
+	g := &httpGetter{ctx: ctx, repoURL: repoURL, opts: opts}
+	return append(getter.Providers{{
+		Schemes: []string{"http", "https"},
+		New:     func(...getter.Option) (getter.Getter, error) { return g, nil },
+	}}, getter.All(settings)...)
+}
+
+// httpGetter is the getter of the http and https chart repos. Unlike the helm
+// one it sends bearer tokens, takes the PEM data from memory and can skip the
+// TLS verification, the helm options are ignored in favour of its own. The
+// downloads are bound to the context.
+type httpGetter struct {
+	ctx     context.Context
+	repoURL string
+	opts    RepoOptions
+}
+
+// Get downloads the URL, the credentials are only sent to the repo host
+// unless they are passed to all hosts
+func (g *httpGetter) Get(href string, _ ...getter.Option) (*bytes.Buffer, error) {
+	client, err := g.opts.httpClient(g.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequestWithContext(g.ctx, http.MethodGet, href, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("User-Agent", "meshinfra")
+	if g.opts.PassCredentialsAll || sameHost(g.repoURL, href) {
+		switch {
+		case g.opts.BearerToken != "":
+			r.Header.Set("Authorization", "Bearer "+g.opts.BearerToken)
+		case g.opts.Username != "" || g.opts.Password != "":
+			r.SetBasicAuth(g.opts.Username, g.opts.Password)
+		}
+	}
+
+	resp, err := client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{code: resp.StatusCode, msg: fmt.Sprintf("failed to fetch %s : %s", href, resp.Status)}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	_, err = io.Copy(buf, resp.Body)
+	return buf, err
+}
+
+// statusError is the failure of a request the server answered with an error
+// status
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// isRefused reports whether the server refused the credentials of the failed
+// request, the cache is never used in place of a refused request
+func isRefused(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && (se.code == http.StatusUnauthorized || se.code == http.StatusForbidden)
+}
+
+// sameHost reports whether the URLs are on the same host
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Host == ub.Host
+}
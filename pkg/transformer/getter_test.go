@@ -0,0 +1,188 @@
+package transformer_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+)
+
+// clientCert returns a self-signed PEM client certificate and its key
+func clientCert(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "meshinfra"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+// tlsRepo serves the chart repo of the directory over TLS to the holders of
+// the credentials, and of the client certificate when it is given
+func tlsRepo(t *testing.T, dir string, clientCA []byte) *httptest.Server {
+	files := http.FileServer(http.Dir(dir))
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		if r.Header.Get("Authorization") != "Bearer token" && (username != "mesh" || password != "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	if clientCA != nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(clientCA)
+		srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	}
+	srv.StartTLS()
+	return srv
+}
+
+func TestHelmTransformRepoOptions(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	cert, key := clientCert(t)
+	srv := tlsRepo(t, charts, nil)
+	defer srv.Close()
+	mtls := tlsRepo(t, charts, cert)
+	defer mtls.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	mtlsCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: mtls.Certificate().Raw})
+	dir := filepath.Dir(settings.RepositoryConfig)
+	caFile, certFile, keyFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, data := range map[string][]byte{caFile: ca, certFile: cert, keyFile: key} {
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		opts    transformer.RepoOptions
+		wantErr bool
+	}{
+		{name: "CA data", opts: transformer.RepoOptions{Username: "mesh", Password: "secret", CAData: ca}},
+		{name: "CA file", opts: transformer.RepoOptions{Username: "mesh", Password: "secret", CAFile: caFile}},
+		{name: "bearer token", opts: transformer.RepoOptions{BearerToken: "token", CAData: ca}},
+		{name: "insecure", opts: transformer.RepoOptions{Username: "mesh", Password: "secret", InsecureSkipTLSVerify: true}},
+		{name: "unknown CA", opts: transformer.RepoOptions{Username: "mesh", Password: "secret"}, wantErr: true},
+		{name: "wrong password", opts: transformer.RepoOptions{Username: "mesh", Password: "wrong", CAData: ca}, wantErr: true},
+		{
+			name:    "client certificate data",
+			address: mtls.URL,
+			opts:    transformer.RepoOptions{BearerToken: "token", CAData: mtlsCA, CertData: cert, KeyData: key},
+		},
+		{
+			name:    "client certificate files",
+			address: mtls.URL,
+			opts:    transformer.RepoOptions{BearerToken: "token", CAData: mtlsCA, CertFile: certFile, KeyFile: keyFile},
+		},
+		{name: "no client certificate", address: mtls.URL, opts: transformer.RepoOptions{BearerToken: "token", CAData: mtlsCA}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := tt.address
+			if address == "" {
+				address = srv.URL
+			}
+			s := settings
+			s.RepositoryCache = filepath.Join(settings.RepositoryCache, tt.name)
+			req := repoRequest(address, s)
+			req.Repo = tt.opts
+
+			_, err := (&transformer.Helm{}).Transform(context.Background(), req)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Transform returned %v, want an error: %t", err, tt.wantErr)
+			}
+		})
+	}
+
+	data, err := ioutil.ReadFile(settings.RepositoryConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") || strings.Contains(string(data), "token") {
+		t.Errorf("the credentials are written to the repo config:\n%s", data)
+	}
+}
+
+func TestHelmTransformSharedClient(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	var (
+		mu             sync.Mutex
+		opened, closed int
+	)
+	srv := httptest.NewUnstartedServer(http.FileServer(http.Dir(charts)))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch state {
+		case http.StateNew:
+			opened++
+		case http.StateClosed:
+			closed++
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	// The index and the chart are downloaded over the same connection, which
+	// is closed once the transform is done
+	if _, err := (&transformer.Helm{}).Transform(context.Background(), repoRequest(srv.URL, settings)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		o, c := opened, closed
+		mu.Unlock()
+		if o == 1 && c == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the transform opened %d connections and left %d open, want a single one closed", o, o-c)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,221 @@
+package transformer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	common "github.com/Aisuko/meshinfra/pkg/common"
+	"github.com/Aisuko/meshinfra/pkg/log"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// renderMu serializes the client-only installs, they write the capabilities
+// shared by all the installs of the process (chartutil.DefaultCapabilities)
+var renderMu sync.Mutex
+
+// ValuesFunc is used to customize the values of the chart before it is rendered
+type ValuesFunc func(req *Request, ch *chart.Chart, vals map[string]interface{}) (map[string]interface{}, error)
+
+// Helm is the Transformer built on the Helm render pipeline, the meshes plug
+// their own behaviour in through the hooks
+type Helm struct {
+	// Values is called with the merged value overrides of the request, it can
+	// be nil. The values it returns win over the profile values of the request.
+	Values ValuesFunc
+}
+
+// Transform renders the chart of the request, the chart repo is added and
+// updated first unless the request points to a local or an in-memory chart.
+// Every network step and the rendering give up as soon as the context is done.
+func (h *Helm) Transform(ctx context.Context, req *Request) (*Result, error) {
+	ctx, done := withHTTPClients(ctx)
+	defer done()
+
+	result, err := h.renderChart(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	release := result.Release
+	objects, err := ParseManifest(release.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Manifest = release.Manifest
+	result.Objects = objects
+	result.Hooks = release.Hooks
+	result.Version = release.Chart.Metadata.Version
+	return result, nil
+}
+
+// requestLogger returns the logger of the request with the fields of its chart,
+// it drops every entry when the request has no logger
+func requestLogger(req *Request) log.Logger {
+	logger := req.Logger
+	if logger == nil {
+		logger = log.Nop()
+	}
+
+	fields := log.Fields{log.FieldRelease: req.ReleaseName}
+	switch {
+	case req.Chart != nil:
+		fields[log.FieldChart] = req.Chart.Name()
+	case req.ChartPath != "":
+		fields[log.FieldChart] = req.ChartPath
+	default:
+		fields[log.FieldChart] = req.ChartName
+		fields[log.FieldRepository] = req.RepoName
+	}
+	return logger.WithFields(fields)
+}
+
+// locateChart returns the local path of the chart of the request, the chart of
+// a repo is taken from the cache
+func locateChart(ctx context.Context, settings *cli.EnvSettings, req *Request) (string, error) {
+	if req.ChartPath != "" {
+		if _, err := os.Stat(req.ChartPath); err != nil {
+			return "", errors.Wrapf(err, "chart path %q not found", req.ChartPath)
+		}
+		return filepath.Abs(req.ChartPath)
+	}
+	// A registry has no index, nothing is added to the repo config
+	if IsOCI(req.ChartRepoAddress) {
+		return locateOCIChart(ctx, settings, req)
+	}
+
+	// Adding the repo refreshes its index, the other repos are only refreshed
+	// on request
+	if err := addRepo(ctx, settings, req); err != nil {
+		return "", err
+	}
+	if req.RefreshAllRepos {
+		if err := updateRepo(ctx, settings, req); err != nil {
+			return "", err
+		}
+	}
+
+	return locateRepoChart(ctx, settings, req, req.Repo.entry(req.RepoName, req.ChartRepoAddress))
+}
+
+// locateRepoChart returns the path of the cached archive of the version of the
+// chart of the request in the cached index of the repo
+func locateRepoChart(ctx context.Context, settings *cli.EnvSettings, req *Request, entry *repoEntry) (string, error) {
+	index, err := loadIndex(settings, entry.URL, repoOptions(req, entry))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed loading the index of the %s repo", entry.Name)
+	}
+	cv, err := resolveVersion(index, req)
+	if err != nil {
+		return "", err
+	}
+	requestLogger(req).Debugf("Resolved the chart version %s", cv.Version)
+
+	return fetchChart(ctx, settings, req, entry, cv)
+}
+
+// renderChart is used to tranform the chart to kubernetes manifest
+func (h *Helm) renderChart(ctx context.Context, req *Request) (*Result, error) {
+	settings := envSettings(req)
+
+	// The chart is only rendered on the client, the install replaces the kube
+	// client and the release storage of the configuration with fakes
+	logger := requestLogger(req)
+	actionConfig := &action.Configuration{Log: logger.Debugf}
+
+	client := action.NewInstall(actionConfig)
+	client.ReleaseName = req.ReleaseName
+
+	vals, err := MergeValues(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Chart != nil || req.ChartPath != "" {
+		if err := verifyLocalChart(req); err != nil {
+			return nil, err
+		}
+	}
+
+	var cp string
+	chartRequested := req.Chart
+	if chartRequested != nil {
+		// The missing dependencies are added to a copy, the chart of the
+		// caller is left as it is
+		chartRequested = copyChart(chartRequested)
+	} else {
+		cp, err = locateChart(ctx, settings, req)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debugf("Chart located at %s", cp)
+
+		// The missing dependencies of the chart are resolved below
+		chartRequested, err = loader.Load(cp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// The chart of a repo is already resolved by its version
+	if req.Chart != nil || req.ChartPath != "" {
+		if err := checkVersion(chartRequested, req); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.Values != nil {
+		vals, err = h.Values(req, chartRequested, vals)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	profileVals, profiles, err := profileValues(chartRequested, req.Profiles)
+	if err != nil {
+		return nil, err
+	}
+	vals = common.MergeMaps(profileVals, vals)
+
+	validInstallableChart, err := common.IsChartInstallable(chartRequested)
+	if !validInstallableChart {
+		return nil, err
+	}
+
+	// The dependencies of a chart directory are resolved next to it
+	dir := ""
+	if fi, err := os.Stat(cp); err == nil && fi.IsDir() {
+		dir = cp
+	}
+	lock, err := resolveDependencies(ctx, settings, req, chartRequested, dir)
+	if err != nil {
+		return nil, err
+	}
+	if deps := chartRequested.Metadata.Dependencies; deps != nil {
+		if err := action.CheckDependencies(chartRequested, deps); err != nil {
+			return nil, err
+		}
+	}
+
+	client.Namespace = namespace(req)
+	client.DryRun = true
+	client.ClientOnly = true
+
+	var rel *release.Release
+	err = runContext(ctx, func() (err error) {
+		renderMu.Lock()
+		defer renderMu.Unlock()
+		rel, err = client.Run(chartRequested, vals)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Release: rel, Profiles: profiles, Lock: lock}, nil
+}
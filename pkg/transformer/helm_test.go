@@ -0,0 +1,541 @@
+package transformer_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Aisuko/meshinfra/pkg/log"
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/Aisuko/meshinfra/pkg/transformer/registrytest"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const chartPath = "testdata/mesh"
+
+func TestHelmTransformLocalChart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := chartutil.Save(ch, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		req  *transformer.Request
+	}{
+		{"directory", &transformer.Request{ChartPath: chartPath}},
+		{"archive", &transformer.Request{ChartPath: archive}},
+		{"in-memory", &transformer.Request{Chart: ch}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.ReleaseName = "mesh"
+			tt.req.Namespace = "mesh-system"
+
+			result, err := (&transformer.Helm{}).Transform(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{"name: mesh-controller", "namespace: mesh-system", "chart: mesh-0.1.0"} {
+				if !strings.Contains(result.Manifest, want) {
+					t.Errorf("manifest does not contain %q:\n%s", want, result.Manifest)
+				}
+			}
+		})
+	}
+}
+
+func TestHelmTransformValues(t *testing.T) {
+	h := &transformer.Helm{
+		Values: func(req *transformer.Request, ch *chart.Chart, vals map[string]interface{}) (map[string]interface{}, error) {
+			vals["controller"] = map[string]interface{}{"replicas": 3}
+			return vals, nil
+		},
+	}
+
+	result, err := h.Transform(context.Background(), &transformer.Request{ReleaseName: "mesh", ChartPath: chartPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Manifest, "replicas: 3") {
+		t.Errorf("manifest does not contain the customized values:\n%s", result.Manifest)
+	}
+}
+
+func TestHelmTransformProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []string
+		values   transformer.ValuesFunc
+		want     []string
+		files    []string
+	}{
+		{
+			name: "none",
+			want: []string{"replicas: 1", "image: mesh/controller:1.0.0\n"},
+		},
+		{
+			name:     "ha",
+			profiles: []string{transformer.ProfileHa},
+			want:     []string{"replicas: 3"},
+			files:    []string{"values-ha.yaml"},
+		},
+		{
+			name:     "later profiles win",
+			profiles: []string{"ha", "values-prod.yaml"},
+			want:     []string{"replicas: 2", "image: mesh/controller:1.0.0-prod"},
+			files:    []string{"values-ha.yaml", "values-prod.yaml"},
+		},
+		{
+			name:     "hook wins",
+			profiles: []string{"prod"},
+			values: func(req *transformer.Request, ch *chart.Chart, vals map[string]interface{}) (map[string]interface{}, error) {
+				vals["controller"] = map[string]interface{}{"replicas": 5}
+				return vals, nil
+			},
+			want:  []string{"replicas: 5", "image: mesh/controller:1.0.0-prod"},
+			files: []string{"values-prod.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &transformer.Helm{Values: tt.values}
+			result, err := h.Transform(context.Background(), &transformer.Request{ReleaseName: "mesh", ChartPath: chartPath, Profiles: tt.profiles})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result.Manifest, want) {
+					t.Errorf("manifest does not contain %q:\n%s", want, result.Manifest)
+				}
+			}
+			if !reflect.DeepEqual(result.Profiles, tt.files) {
+				t.Errorf("Transform applied the profiles %v, want %v", result.Profiles, tt.files)
+			}
+		})
+	}
+}
+
+func TestHelmTransformValuesLayering(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  transformer.Values
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "profile wins over chart defaults",
+			want: []string{"replicas: 3", "image: mesh/controller:1.0.0\n"},
+		},
+		{
+			name:   "values files win over profile",
+			values: transformer.Values{ValueFiles: []string{"testdata/values/replicas.yaml"}},
+			want:   []string{"replicas: 4"},
+		},
+		{
+			name: "set wins over values files",
+			values: transformer.Values{
+				ValueFiles: []string{"testdata/values/replicas.yaml"},
+				Set:        []string{"controller.replicas=6"},
+			},
+			want: []string{"replicas: 6"},
+		},
+		{
+			name:    "null deletes the chart default",
+			values:  transformer.Values{Set: []string{"controller.image=null"}},
+			want:    []string{"replicas: 3"},
+			notWant: []string{"mesh/controller"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := (&transformer.Helm{}).Transform(context.Background(), &transformer.Request{
+				ReleaseName: "mesh",
+				ChartPath:   chartPath,
+				Values:      tt.values,
+				Profiles:    []string{transformer.ProfileHa},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result.Manifest, want) {
+					t.Errorf("manifest does not contain %q:\n%s", want, result.Manifest)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(result.Manifest, notWant) {
+					t.Errorf("manifest should not contain %q:\n%s", notWant, result.Manifest)
+				}
+			}
+		})
+	}
+}
+
+func TestHelmTransformMissingProfile(t *testing.T) {
+	_, err := (&transformer.Helm{}).Transform(context.Background(), &transformer.Request{ReleaseName: "mesh", ChartPath: chartPath, Profiles: []string{"staging"}})
+	if err == nil {
+		t.Fatal("Transform with a missing profile should fail")
+	}
+	if !strings.Contains(err.Error(), "values-staging.yaml") || !strings.Contains(err.Error(), "available profiles: ha, prod") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHelmTransformMissingChart(t *testing.T) {
+	_, err := (&transformer.Helm{}).Transform(context.Background(), &transformer.Request{ReleaseName: "mesh", ChartPath: "testdata/missing"})
+	if err == nil {
+		t.Fatal("Transform of a missing chart path should fail")
+	}
+}
+
+// chartRepo serves the test chart from a chart repo and returns its address,
+// the chart is packaged in every version or in its own version
+func chartRepo(t *testing.T, dir string, versions ...string) *httptest.Server {
+	writeChartRepo(t, dir, versions...)
+	return httptest.NewServer(http.FileServer(http.Dir(dir)))
+}
+
+// writeChartRepo writes the chart repo of the versions of the test chart to
+// the directory, the index refers to the archives by relative URLs
+func writeChartRepo(t *testing.T, dir string, versions ...string) {
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) == 0 {
+		versions = []string{ch.Metadata.Version}
+	}
+	for _, version := range versions {
+		ch.Metadata.Version = version
+		if _, err := chartutil.Save(ch, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index, err := repo.IndexDirectory(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.WriteFile(filepath.Join(dir, "index.yaml"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHelmTransformChartRepo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv := chartRepo(t, filepath.Join(dir, "charts"))
+	defer srv.Close()
+
+	settings := transformer.Settings{
+		RepositoryConfig: filepath.Join(dir, "helm", "repositories.yaml"),
+		RepositoryCache:  filepath.Join(dir, "helm", "repository"),
+	}
+	result, err := (&transformer.Helm{}).Transform(context.Background(), &transformer.Request{
+		ChartName:        "mesh",
+		ReleaseName:      "mesh",
+		RepoName:         "local",
+		ChartRepoAddress: srv.URL,
+		Settings:         settings,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Manifest, "namespace: default") {
+		t.Errorf("manifest is not rendered into the default namespace:\n%s", result.Manifest)
+	}
+
+	f, err := repo.LoadFile(settings.RepositoryConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Has("local") {
+		t.Error("chart repo is not added to the repo config of the settings")
+	}
+}
+
+func TestHelmTransformChartVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv := chartRepo(t, filepath.Join(dir, "charts"), "0.1.0", "1.0.0", "1.1.0", "2.0.0-beta.1")
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		version string
+		devel   bool
+		want    string
+	}{
+		{name: "latest", want: "1.1.0"},
+		{name: "exact", version: "1.0.0", want: "1.0.0"},
+		{name: "range", version: "^1.0", want: "1.1.0"},
+		{name: "patch range", version: "~1.0", want: "1.0.0"},
+		{name: "range below", version: "<1.0.0", want: "0.1.0"},
+		{name: "devel", devel: true, want: "2.0.0-beta.1"},
+		{name: "prerelease range", version: "^2.0.0-0", want: "2.0.0-beta.1"},
+		{name: "devel outside the range", version: "^1.0.0", devel: true, want: "1.1.0"},
+		{name: "prerelease", version: "2.0.0-beta.1", want: "2.0.0-beta.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := (&transformer.Helm{}).Transform(context.Background(), &transformer.Request{
+				ChartName:        "mesh",
+				ReleaseName:      "mesh",
+				RepoName:         "local",
+				ChartRepoAddress: srv.URL,
+				Version:          tt.version,
+				Devel:            tt.devel,
+				Settings: transformer.Settings{
+					RepositoryConfig: filepath.Join(dir, "helm", "repositories.yaml"),
+					RepositoryCache:  filepath.Join(dir, "helm", "repository"),
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Version != tt.want {
+				t.Errorf("Transform rendered the version %s, want %s", result.Version, tt.want)
+			}
+			if want := "chart: mesh-" + tt.want; !strings.Contains(result.Manifest, want) {
+				t.Errorf("manifest does not contain %q:\n%s", want, result.Manifest)
+			}
+		})
+	}
+}
+
+func TestHelmTransformChartVersionMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv := chartRepo(t, filepath.Join(dir, "charts"), "1.0.0", "2.0.0-beta.1")
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		req  *transformer.Request
+	}{
+		{"no match in the repo", &transformer.Request{ChartName: "mesh", RepoName: "local", ChartRepoAddress: srv.URL, Version: ">=2.0.0"}},
+		{"invalid version", &transformer.Request{ChartName: "mesh", RepoName: "local", ChartRepoAddress: srv.URL, Version: "latest"}},
+		{"local chart", &transformer.Request{ChartPath: chartPath, Version: "^1.0.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.ReleaseName = "mesh"
+			tt.req.Settings = transformer.Settings{
+				RepositoryConfig: filepath.Join(dir, "helm", "repositories.yaml"),
+				RepositoryCache:  filepath.Join(dir, "helm", "repository"),
+			}
+			if _, err := (&transformer.Helm{}).Transform(context.Background(), tt.req); err == nil {
+				t.Fatal("Transform should fail")
+			}
+		})
+	}
+}
+
+func TestListVersions(t *testing.T) {
+	charts, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	writeChartRepo(t, charts, "0.1.0", "1.0.0", "1.1.0", "2.0.0-beta.1")
+	srv := newCountingRepo(charts)
+	defer srv.Close()
+
+	reg := registrytest.New()
+	defer reg.Close()
+	pushCharts(t, reg, "1.0.0", "1.1.0", "2.0.0-beta.1")
+
+	tests := []struct {
+		name    string
+		req     *transformer.Request
+		version string
+		devel   bool
+		want    []string
+	}{
+		{name: "repo", req: repoRequest(srv.URL, settings), want: []string{"1.1.0", "1.0.0", "0.1.0"}},
+		{name: "repo range", req: repoRequest(srv.URL, settings), version: "^1.0.0", want: []string{"1.1.0", "1.0.0"}},
+		{name: "repo devel", req: repoRequest(srv.URL, settings), devel: true, want: []string{"2.0.0-beta.1", "1.1.0", "1.0.0", "0.1.0"}},
+		{name: "registry", req: ociRequest(reg.Address("meshes"), settings), want: []string{"1.1.0", "1.0.0"}},
+		{name: "registry devel", req: ociRequest(reg.Address("meshes"), settings), devel: true, want: []string{"2.0.0-beta.1", "1.1.0", "1.0.0"}},
+		{name: "registry prerelease range", req: ociRequest(reg.Address("meshes"), settings), version: "~2.0.0-0", want: []string{"2.0.0-beta.1"}},
+		{name: "registry devel range", req: ociRequest(reg.Address("meshes"), settings), version: "~2.0.0", devel: true, want: []string{}},
+		{name: "local chart", req: &transformer.Request{ChartPath: chartPath}, want: []string{"0.1.0"}},
+		{name: "no match", req: repoRequest(srv.URL, settings), version: ">=3.0.0", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Version, tt.req.Devel = tt.version, tt.devel
+			versions, err := transformer.ListVersions(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(versions, tt.want) {
+				t.Errorf("ListVersions returned %v, want %v", versions, tt.want)
+			}
+		})
+	}
+
+	req := repoRequest(srv.URL, settings)
+	req.ChartName = "unknown"
+	if _, err := transformer.ListVersions(context.Background(), req); err == nil {
+		t.Error("ListVersions of an unknown chart should fail")
+	}
+}
+
+func TestHelmTransformCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := (&transformer.Helm{}).Transform(ctx, &transformer.Request{ReleaseName: "mesh", ChartPath: chartPath})
+	if err != context.Canceled {
+		t.Fatalf("Transform returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestHelmTransformDeadline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The chart repo never answers, it only sees the download given up
+	release := make(chan struct{})
+	gone := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(gone)
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = (&transformer.Helm{}).Transform(ctx, &transformer.Request{
+		ChartName:        "mesh",
+		ReleaseName:      "mesh",
+		RepoName:         "slow",
+		ChartRepoAddress: srv.URL,
+		Settings: transformer.Settings{
+			RepositoryConfig: filepath.Join(dir, "repositories.yaml"),
+			RepositoryCache:  filepath.Join(dir, "repository"),
+		},
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Transform returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Transform returned after %v, long after the deadline", elapsed)
+	}
+	select {
+	case <-gone:
+	case <-time.After(5 * time.Second):
+		t.Error("the download of the index is kept running after the deadline")
+	}
+}
+
+func TestHelmTransformConcurrentNamespaces(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(ns string) {
+			defer wg.Done()
+			result, err := (&transformer.Helm{}).Transform(context.Background(), &transformer.Request{
+				ReleaseName: "mesh",
+				Namespace:   ns,
+				ChartPath:   chartPath,
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if strings.Count(result.Manifest, "namespace: "+ns+"\n") != 2 {
+				t.Errorf("manifest is not rendered into the %s namespace:\n%s", ns, result.Manifest)
+			}
+		}(fmt.Sprintf("mesh-%d", i))
+	}
+	wg.Wait()
+}
+
+func TestHelmTransformLogger(t *testing.T) {
+	logger := &recordLogger{}
+	_, err := (&transformer.Helm{}).Transform(context.Background(), &transformer.Request{ReleaseName: "mesh", ChartPath: chartPath, Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logger.entries) == 0 {
+		t.Fatal("nothing is logged")
+	}
+	for _, entry := range logger.entries {
+		if entry.fields[log.FieldChart] != chartPath || entry.fields[log.FieldRelease] != "mesh" {
+			t.Errorf("the entry %q is logged with the fields %v", entry.msg, entry.fields)
+		}
+	}
+}
+
+func TestHelmTransformObjects(t *testing.T) {
+	result, err := (&transformer.Helm{}).Transform(context.Background(), &transformer.Request{ReleaseName: "mesh", ChartPath: chartPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds []string
+	for _, obj := range result.Objects {
+		kinds = append(kinds, obj.Kind+"/"+obj.Name)
+		if obj.Namespace != "default" || obj.Source == "" || obj.Raw == "" {
+			t.Errorf("object is not fully parsed: %#v", obj)
+		}
+	}
+	if want := []string{"ConfigMap/mesh-config", "Deployment/mesh-controller"}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("Transform returned the objects %v, want %v", kinds, want)
+	}
+
+	if len(result.Hooks) != 1 || result.Hooks[0].Name != "mesh-check" {
+		t.Errorf("Transform returned the hooks %v, want the mesh-check hook", result.Hooks)
+	}
+}
@@ -0,0 +1,12 @@
+package transformer
+
+import "context"
+
+//go:generate mockgen -source ./interfaces.go -destination ./mocks/mock_interfaces.go
+
+// Transformer interface is used to define the way how to transform the chart of a mesh
+type Transformer interface {
+	// Transform renders the chart of the request, it gives up as soon as the
+	// context is done
+	Transform(ctx context.Context, req *Request) (*Result, error)
+}
@@ -0,0 +1,83 @@
+package transformer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// sourcePrefix is the comment helm puts in front of every rendered object
+const sourcePrefix = "# Source: "
+
+// Object is a single kubernetes object of the rendered manifest
+type Object struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+	// Source is the path of the chart template the object was rendered from
+	Source string
+	// Raw is the YAML of the object
+	Raw string
+}
+
+// head is the part of an object needed to identify it
+type head struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+}
+
+// ParseManifest splits the manifest into its objects, they keep the order of
+// the manifest and the empty documents are dropped
+func ParseManifest(manifest string) ([]Object, error) {
+	docs := releaseutil.SplitManifests(manifest)
+
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	objects := make([]Object, 0, len(keys))
+	for _, k := range keys {
+		obj, err := parseObject(docs[k])
+		if err != nil {
+			return nil, err
+		}
+		if obj.Kind == "" {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// parseObject decodes a single document of the manifest
+func parseObject(doc string) (Object, error) {
+	var obj Object
+	for _, line := range strings.Split(doc, "\n") {
+		if strings.HasPrefix(line, sourcePrefix) {
+			obj.Source = strings.TrimSpace(strings.TrimPrefix(line, sourcePrefix))
+			break
+		}
+	}
+
+	var h head
+	if err := yaml.Unmarshal([]byte(doc), &h); err != nil {
+		return obj, errors.Wrapf(err, "failed parsing the object rendered from %q", obj.Source)
+	}
+
+	obj.APIVersion = h.APIVersion
+	obj.Kind = h.Kind
+	obj.Namespace = h.Metadata.Namespace
+	obj.Name = h.Metadata.Name
+	obj.Raw = strings.TrimSpace(doc) + "\n"
+	return obj, nil
+}
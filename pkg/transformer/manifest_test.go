@@ -0,0 +1,59 @@
+package transformer
+
+import (
+	"reflect"
+	"testing"
+)
+
+const manifest = `---
+# Source: mesh/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: mesh-config
+  namespace: mesh-system
+data:
+  chart: mesh-0.1.0
+---
+# Source: mesh/templates/empty.yaml
+---
+# Source: mesh/templates/crd.yaml
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: meshes.mesh.io
+`
+
+func TestParseManifest(t *testing.T) {
+	objects, err := ParseManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Object{
+		{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Namespace:  "mesh-system",
+			Name:       "mesh-config",
+			Source:     "mesh/templates/configmap.yaml",
+			Raw:        "# Source: mesh/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: mesh-config\n  namespace: mesh-system\ndata:\n  chart: mesh-0.1.0\n",
+		},
+		{
+			APIVersion: "apiextensions.k8s.io/v1beta1",
+			Kind:       "CustomResourceDefinition",
+			Name:       "meshes.mesh.io",
+			Source:     "mesh/templates/crd.yaml",
+			Raw:        "# Source: mesh/templates/crd.yaml\napiVersion: apiextensions.k8s.io/v1beta1\nkind: CustomResourceDefinition\nmetadata:\n  name: meshes.mesh.io\n",
+		},
+	}
+	if !reflect.DeepEqual(objects, want) {
+		t.Errorf("ParseManifest returned %#v, want %#v", objects, want)
+	}
+}
+
+func TestParseManifestInvalid(t *testing.T) {
+	if _, err := ParseManifest("---\nkind: [ConfigMap\n"); err == nil {
+		t.Fatal("ParseManifest of an invalid document should fail")
+	}
+}
@@ -0,0 +1,52 @@
+package transformer
+
+import (
+	"reflect"
+
+	common "github.com/Aisuko/meshinfra/pkg/common"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// MeshOptions copies the mesh specific options of the request into opts, a
+// pointer to the options type of the mesh. The options of the request are
+// either of that type or a pointer to it, opts is left as it is when the
+// request has none.
+func MeshOptions(req *Request, mesh string, opts interface{}) error {
+	if req.Options == nil {
+		return nil
+	}
+	dst := reflect.ValueOf(opts).Elem()
+	src := reflect.ValueOf(req.Options)
+	if src.Kind() == reflect.Ptr && src.Type().Elem() == dst.Type() {
+		if src.IsNil() {
+			return nil
+		}
+		src = src.Elem()
+	}
+	if src.Type() != dst.Type() {
+		return errors.Errorf("%s options are expected, got %T", mesh, req.Options)
+	}
+	dst.Set(src)
+	return nil
+}
+
+// MeshValues returns the values hook of a mesh chart whose HA request and
+// options only add values to the chart. The values of the options win over
+// the HA values and the value overrides of the request win over both. The HA
+// request fails when ha is nil, the chart has no HA setup then.
+func MeshValues(mesh string, ha map[string]interface{}, options func(req *Request) (map[string]interface{}, error)) ValuesFunc {
+	return func(req *Request, _ *chart.Chart, vals map[string]interface{}) (map[string]interface{}, error) {
+		optVals, err := options(req)
+		if err != nil {
+			return nil, err
+		}
+		if req.IsHa {
+			if ha == nil {
+				return nil, errors.Errorf("the %s chart has no HA setup", mesh)
+			}
+			optVals = common.MergeMaps(ha, optVals)
+		}
+		return common.MergeMaps(optVals, vals), nil
+	}
+}
@@ -0,0 +1,60 @@
+package transformer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+)
+
+type meshOptions struct {
+	Mode string
+}
+
+func TestMeshOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options interface{}
+		want    meshOptions
+		err     bool
+	}{
+		{name: "none", want: meshOptions{Mode: "default"}},
+		{name: "value", options: meshOptions{Mode: "zone"}, want: meshOptions{Mode: "zone"}},
+		{name: "pointer", options: &meshOptions{Mode: "zone"}, want: meshOptions{Mode: "zone"}},
+		{name: "nil pointer", options: (*meshOptions)(nil), want: meshOptions{Mode: "default"}},
+		{name: "other type", options: "zone", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := meshOptions{Mode: "default"}
+			err := transformer.MeshOptions(&transformer.Request{Options: tt.options}, "mesh", &opts)
+			if (err != nil) != tt.err {
+				t.Fatalf("MeshOptions returned %v", err)
+			}
+			if !tt.err && opts != tt.want {
+				t.Errorf("MeshOptions returned %v, want %v", opts, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeshValues(t *testing.T) {
+	ha := map[string]interface{}{"replicas": 3, "mode": "ha"}
+	options := func(*transformer.Request) (map[string]interface{}, error) {
+		return map[string]interface{}{"mode": "zone"}, nil
+	}
+	overrides := map[string]interface{}{"replicas": 5}
+
+	vals, err := transformer.MeshValues("mesh", ha, options)(&transformer.Request{IsHa: true}, nil, overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"replicas": 5, "mode": "zone"}; !reflect.DeepEqual(vals, want) {
+		t.Errorf("MeshValues returned %v, want %v", vals, want)
+	}
+
+	if _, err := transformer.MeshValues("mesh", nil, options)(&transformer.Request{IsHa: true}, nil, overrides); err == nil {
+		t.Error("MeshValues of a chart without HA should fail the HA request")
+	}
+}
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./interfaces.go
+
+// Package mock_transformer is a generated GoMock package.
+package mock_transformer
+
+import (
+	context "context"
+	transformer "github.com/Aisuko/meshinfra/pkg/transformer"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockTransformer is a mock of Transformer interface
+type MockTransformer struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransformerMockRecorder
+}
+
+// MockTransformerMockRecorder is the mock recorder for MockTransformer
+type MockTransformerMockRecorder struct {
+	mock *MockTransformer
+}
+
+// NewMockTransformer creates a new mock instance
+func NewMockTransformer(ctrl *gomock.Controller) *MockTransformer {
+	mock := &MockTransformer{ctrl: ctrl}
+	mock.recorder = &MockTransformerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTransformer) EXPECT() *MockTransformerMockRecorder {
+	return m.recorder
+}
+
+// Transform mocks base method
+func (m *MockTransformer) Transform(ctx context.Context, req *transformer.Request) (*transformer.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transform", ctx, req)
+	ret0, _ := ret[0].(*transformer.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transform indicates an expected call of Transform
+func (mr *MockTransformerMockRecorder) Transform(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transform", reflect.TypeOf((*MockTransformer)(nil).Transform), ctx, req)
+}
@@ -0,0 +1,437 @@
+package transformer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// OCIScheme is the scheme of the chart repo addresses of an OCI registry, like
+// oci://ghcr.io/meshes where the chart name is the last part of the repository
+const OCIScheme = "oci"
+
+// The media types of the OCI artifact of a chart
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	chartLayerMediaType  = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	// legacyChartLayerMediaType is the chart layer pushed by helm 3.0 and 3.1
+	legacyChartLayerMediaType = "application/tar+gzip"
+)
+
+// ociCacheDir is the cache of the tags and the manifests of the registries,
+// the chart layers are cached with the chart archives of the repos
+const ociCacheDir = "oci"
+
+// RegistryOptions are the options of the OCI registry of an oci:// chart repo
+// address. The credentials of the registry config of the settings, a docker
+// config.json file, are used when the options have none. The TLS settings are
+// the ones of the RepoOptions of the request.
+type RegistryOptions struct {
+	Username string
+	Password string
+	// PlainHTTP talks to the registry over http, like to a local registry
+	PlainHTTP bool
+}
+
+// IsOCI reports whether the chart repo address is the one of an OCI registry
+func IsOCI(address string) bool {
+	return strings.HasPrefix(address, OCIScheme+"://")
+}
+
+// ociRef is the repository of a chart in a registry
+type ociRef struct {
+	host       string
+	repository string
+}
+
+// parseOCIRef returns the repository of the chart, the chart name is appended
+// to the address unless it is empty
+func parseOCIRef(address, chartName string) (*ociRef, error) {
+	ref := strings.Trim(strings.TrimPrefix(address, OCIScheme+"://"), "/")
+	if chartName != "" {
+		ref += "/" + chartName
+	}
+	i := strings.Index(ref, "/")
+	if i <= 0 || i == len(ref)-1 {
+		return nil, errors.Errorf("invalid OCI chart reference %q, want oci://<registry>/<repository>", address)
+	}
+	return &ociRef{host: ref[:i], repository: ref[i+1:]}, nil
+}
+
+func (r *ociRef) String() string {
+	return OCIScheme + "://" + r.host + "/" + r.repository
+}
+
+// ociManifest is the part of an OCI image manifest needed to pull a chart
+type ociManifest struct {
+	Layers []struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// locateOCIChart returns the path of the cached archive of the chart of the
+// request in its registry, the version is resolved from the tags
+func locateOCIChart(ctx context.Context, settings *cli.EnvSettings, req *Request) (string, error) {
+	logger := requestLogger(req)
+	ref, err := parseOCIRef(req.ChartRepoAddress, req.ChartName)
+	if err != nil {
+		return "", err
+	}
+	c, err := newRegistryClient(ctx, settings, req, ref)
+	if err != nil {
+		return "", err
+	}
+
+	tag, err := c.resolveTag(ctx)
+	if err != nil {
+		return "", err
+	}
+	logger.Debugf("Resolved the chart version %s", tag)
+
+	data, err := c.cachedGet(ctx, "/manifests/"+tag, ociManifestMediaType, filepath.Join("manifests", tag+".json"))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed pulling the manifest of %s:%s", ref, tag)
+	}
+	var manifest ociManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", errors.Wrapf(err, "failed parsing the manifest of %s:%s", ref, tag)
+	}
+	sum := sha256.Sum256(data)
+	if err := c.verifyCosign(ctx, hex.EncodeToString(sum[:])); err != nil {
+		return "", err
+	}
+
+	digest := ""
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == chartLayerMediaType || layer.MediaType == legacyChartLayerMediaType {
+			digest = layer.Digest
+			break
+		}
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		return "", errors.Errorf("%s:%s is not a chart, it has no sha256 chart layer", ref, tag)
+	}
+	return c.fetchLayer(ctx, strings.TrimPrefix(digest, "sha256:"))
+}
+
+// registryClient pulls the chart of a repository over the OCI distribution API
+type registryClient struct {
+	settings *cli.EnvSettings
+	req      *Request
+	ref      *ociRef
+	base     string
+	username string
+	password string
+	client   *http.Client
+	// authorization is the Authorization header once the registry asked for it
+	authorization string
+}
+
+func newRegistryClient(ctx context.Context, settings *cli.EnvSettings, req *Request, ref *ociRef) (*registryClient, error) {
+	c := &registryClient{
+		settings: settings,
+		req:      req,
+		ref:      ref,
+		base:     "https://" + ref.host,
+		username: req.Registry.Username,
+		password: req.Registry.Password,
+	}
+	var err error
+	if c.client, err = req.Repo.httpClient(ctx); err != nil {
+		return nil, err
+	}
+	if req.Registry.PlainHTTP {
+		c.base = "http://" + ref.host
+	}
+
+	if c.username == "" && c.password == "" {
+		c.username, c.password, err = registryCredentials(settings.RegistryConfig, ref.host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// resolveTag returns the latest tag of the repository which matches the
+// version of the request. Helm tags the versions with "_" in place of "+".
+func (c *registryClient) resolveTag(ctx context.Context) (string, error) {
+	m, err := newVersionMatcher(c.req)
+	if err != nil {
+		return "", err
+	}
+	versions, err := c.versions(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	available := make([]string, 0, len(versions))
+	for _, v := range versions {
+		if m.match(v.Original()) {
+			return strings.Replace(v.Original(), "+", "_", -1), nil
+		}
+		available = append(available, v.Original())
+	}
+	return "", errors.Errorf("no version of the chart %s matches %q (available: %s)",
+		c.ref, versionOrLatest(c.req), strings.Join(available, ", "))
+}
+
+// versions returns the semver versions of the tags of the repository, from the
+// latest on, the other tags are skipped
+func (c *registryClient) versions(ctx context.Context) ([]*semver.Version, error) {
+	data, err := c.cachedGet(ctx, "/tags/list", "application/json", "tags.json")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed listing the tags of %s", c.ref)
+	}
+	var list struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, errors.Wrapf(err, "failed parsing the tags of %s", c.ref)
+	}
+
+	versions := make([]*semver.Version, 0, len(list.Tags))
+	for _, tag := range list.Tags {
+		if v, err := semver.NewVersion(strings.Replace(tag, "_", "+", -1)); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.Sort(sort.Reverse(semver.Collection(versions)))
+	return versions, nil
+}
+
+// cachedGet returns the response of the path of the repository, the cached one
+// is used while it is fresh. A failed request falls back to the cached
+// response, however old it is, like a failed index refresh, unless the
+// registry refused the credentials.
+func (c *registryClient) cachedGet(ctx context.Context, path, accept, cacheName string) ([]byte, error) {
+	logger := requestLogger(c.req)
+	cacheFile := filepath.Join(c.settings.RepositoryCache, ociCacheDir, credentialKey(c.credentials()), cacheKey(c.ref.String()), cacheName)
+
+	fi, statErr := os.Stat(cacheFile)
+	if statErr == nil {
+		if ttl := indexTTL(c.req); ttl > 0 && time.Since(fi.ModTime()) < ttl {
+			return ioutil.ReadFile(cacheFile)
+		}
+	}
+
+	data, err := c.get(ctx, "/v2/"+c.ref.repository+path, accept)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if statErr == nil && !isRefused(err) {
+			logger.Warnf("Request %s%s failed, the cached response is used: %s", c.ref, path, err)
+			return ioutil.ReadFile(cacheFile)
+		}
+		return nil, err
+	}
+	if err := writeFile(cacheFile, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// fetchLayer returns the path of the cached archive of the chart layer with
+// the digest, it is pulled unless the cache holds it already
+func (c *registryClient) fetchLayer(ctx context.Context, digest string) (string, error) {
+	logger := requestLogger(c.req)
+	path := archivePath(c.settings, c.credentials(), digest)
+	if sum, err := fileDigest(path); err == nil && sum == digest {
+		logger.Debugf("Use the cached chart layer of %s", c.ref)
+		return path, nil
+	}
+
+	data, err := c.get(ctx, "/v2/"+c.ref.repository+"/blobs/sha256:"+digest, "")
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		return "", errors.Wrapf(err, "failed pulling the chart layer of %s", c.ref)
+	}
+
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != digest {
+		return "", errors.Errorf("the digest of the chart layer of %s is sha256:%s, the manifest expects sha256:%s", c.ref, got, digest)
+	}
+	if err := writeFile(path, data); err != nil {
+		return "", err
+	}
+	logger.Debugf("Cached the chart layer of %s", c.ref)
+	return path, nil
+}
+
+// credentials returns the credentials the client pulls with, the ones of the
+// registry and the client certificate of the request
+func (c *registryClient) credentials() RepoOptions {
+	opts := c.req.Repo
+	opts.Username, opts.Password, opts.BearerToken = c.username, c.password, ""
+	return opts
+}
+
+// get returns the body of the path of the registry, the client authorizes
+// itself with the challenge of the registry when it is unauthorized
+func (c *registryClient) get(ctx context.Context, path, accept string) ([]byte, error) {
+	resp, err := c.do(ctx, c.base+path, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.authorization == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := c.authorize(ctx, challenge); err != nil {
+			return nil, &statusError{code: http.StatusUnauthorized, msg: err.Error()}
+		}
+		if resp, err = c.do(ctx, c.base+path, accept); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{code: resp.StatusCode, msg: fmt.Sprintf("GET %s%s: %s", c.base, path, resp.Status)}
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (c *registryClient) do(ctx context.Context, u, accept string) (*http.Response, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	if c.authorization != "" {
+		r.Header.Set("Authorization", c.authorization)
+	}
+	return c.client.Do(r)
+}
+
+// challengeParam matches the parameters of a WWW-Authenticate challenge
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize answers the WWW-Authenticate challenge of the registry, with the
+// credentials for a basic challenge or with a token of the realm for a bearer one
+func (c *registryClient) authorize(ctx context.Context, challenge string) error {
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	switch scheme {
+	case "basic":
+		if c.username == "" && c.password == "" {
+			return errors.Errorf("the registry %s requires credentials", c.ref.host)
+		}
+		c.authorization = "Basic " + basicAuth(c.username, c.password)
+		return nil
+	case "bearer":
+	default:
+		return errors.Errorf("the registry %s is unauthorized, unknown challenge %q", c.ref.host, challenge)
+	}
+
+	params := map[string]string{}
+	for _, m := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return errors.Errorf("the registry %s has an invalid token realm %q", c.ref.host, params["realm"])
+	}
+	q := realm.Query()
+	if service := params["service"]; service != "" {
+		q.Set("service", service)
+	}
+	q.Set("scope", "repository:"+c.ref.repository+":pull")
+	realm.RawQuery = q.Encode()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.username != "" || c.password != "" {
+		r.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed getting a token of the registry %s: %s", c.ref.host, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return errors.Wrapf(err, "failed parsing the token of the registry %s", c.ref.host)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	c.authorization = "Bearer " + token.Token
+	return nil
+}
+
+func basicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
+
+// registryCredentials returns the credentials of the host in the registry
+// config, a docker config.json file. A missing config has no credentials.
+func registryCredentials(configFile, host string) (string, string, error) {
+	data, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	var config struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", "", errors.Wrapf(err, "failed parsing the registry config %s", configFile)
+	}
+
+	for key, auth := range config.Auths {
+		if strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://"), "/") != host {
+			continue
+		}
+		if auth.Auth == "" {
+			return auth.Username, auth.Password, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "invalid credentials of %s in the registry config", host)
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return "", "", errors.Errorf("invalid credentials of %s in the registry config", host)
+		}
+		return parts[0], parts[1], nil
+	}
+	return "", "", nil
+}
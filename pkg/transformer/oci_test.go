@@ -0,0 +1,194 @@
+package transformer_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/Aisuko/meshinfra/pkg/transformer/registrytest"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// pushCharts pushes the versions of the test chart to the meshes namespace of
+// the registry
+func pushCharts(t *testing.T, reg *registrytest.Registry, versions ...string) {
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range versions {
+		ch.Metadata.Version = version
+		if err := reg.Push("meshes", ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func ociRequest(address string, settings transformer.Settings) *transformer.Request {
+	return &transformer.Request{
+		ChartName:        "mesh",
+		ReleaseName:      "mesh",
+		ChartRepoAddress: address,
+		Registry:         transformer.RegistryOptions{PlainHTTP: true},
+		Settings:         settings,
+	}
+}
+
+func TestHelmTransformOCI(t *testing.T) {
+	_, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	reg := registrytest.New()
+	defer reg.Close()
+	pushCharts(t, reg, "1.0.0", "1.1.0", "2.0.0-beta.1")
+
+	tests := []struct {
+		name    string
+		address string
+		chart   string
+		version string
+		devel   bool
+		want    string
+	}{
+		{name: "latest", address: reg.Address("meshes"), chart: "mesh", want: "1.1.0"},
+		{name: "range", address: reg.Address("meshes"), chart: "mesh", version: "~1.0", want: "1.0.0"},
+		{name: "devel", address: reg.Address("meshes"), chart: "mesh", devel: true, want: "2.0.0-beta.1"},
+		{name: "chart in the address", address: reg.Address("meshes/mesh"), version: "1.0.0", want: "1.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := ociRequest(tt.address, settings)
+			req.ChartName = tt.chart
+			req.Version = tt.version
+			req.Devel = tt.devel
+
+			result, err := (&transformer.Helm{}).Transform(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Version != tt.want {
+				t.Errorf("Transform rendered the version %s, want %s", result.Version, tt.want)
+			}
+			if want := "chart: mesh-" + tt.want; !strings.Contains(result.Manifest, want) {
+				t.Errorf("manifest does not contain %q:\n%s", want, result.Manifest)
+			}
+		})
+	}
+
+	if _, err := os.Stat(settings.RepositoryConfig); !os.IsNotExist(err) {
+		t.Errorf("the registry is added to the repo config: %v", err)
+	}
+}
+
+func TestHelmTransformOCIAuth(t *testing.T) {
+	_, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	reg := registrytest.NewWithAuth("mesh", "secret")
+	defer reg.Close()
+	pushCharts(t, reg, "1.0.0")
+
+	config := filepath.Join(filepath.Dir(settings.RepositoryConfig), "registry.json")
+	auth := base64.StdEncoding.EncodeToString([]byte("mesh:secret"))
+	if err := os.MkdirAll(filepath.Dir(config), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(config, []byte(`{"auths":{"`+reg.Host()+`":{"auth":"`+auth+`"}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		registry transformer.RegistryOptions
+		config   string
+		wantErr  bool
+	}{
+		{name: "credentials", registry: transformer.RegistryOptions{Username: "mesh", Password: "secret"}},
+		{name: "registry config", config: config},
+		{name: "no credentials", wantErr: true},
+		{name: "wrong credentials", registry: transformer.RegistryOptions{Username: "mesh", Password: "wrong"}, config: config, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := settings
+			s.RegistryConfig = tt.config
+			if s.RegistryConfig == "" {
+				s.RegistryConfig = filepath.Join(filepath.Dir(config), "missing.json")
+			}
+			// Every case pulls from the registry
+			s.IndexTTL = -1
+			s.RepositoryCache = filepath.Join(settings.RepositoryCache, tt.name)
+
+			req := ociRequest(reg.Address("meshes"), s)
+			req.Registry.Username = tt.registry.Username
+			req.Registry.Password = tt.registry.Password
+
+			_, err := (&transformer.Helm{}).Transform(context.Background(), req)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Transform returned %v, want an error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHelmTransformOCICacheCredentials(t *testing.T) {
+	_, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	reg := registrytest.NewWithAuth("mesh", "secret")
+	defer reg.Close()
+	pushCharts(t, reg, "1.0.0")
+	settings.RegistryConfig = filepath.Join(filepath.Dir(settings.RepositoryConfig), "missing.json")
+
+	req := ociRequest(reg.Address("meshes"), settings)
+	req.Registry.Username, req.Registry.Password = "mesh", "secret"
+	if err := transformer.Prewarm(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tags, the manifest and the layer cached with the credentials are
+	// theirs only, a refused pull does not fall back to them
+	for _, ttl := range []time.Duration{0, -1} {
+		s := settings
+		s.IndexTTL = ttl
+		if _, err := (&transformer.Helm{}).Transform(context.Background(), ociRequest(reg.Address("meshes"), s)); err == nil {
+			t.Errorf("Transform without credentials (TTL %s) should fail", ttl)
+		}
+		req := ociRequest(reg.Address("meshes"), s)
+		req.Registry.Username, req.Registry.Password = "mesh", "wrong"
+		if _, err := (&transformer.Helm{}).Transform(context.Background(), req); err == nil {
+			t.Errorf("Transform with the wrong credentials (TTL %s) should fail", ttl)
+		}
+	}
+}
+
+func TestHelmTransformOCIOffline(t *testing.T) {
+	_, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	reg := registrytest.New()
+	pushCharts(t, reg, "1.0.0")
+	address := reg.Address("meshes")
+
+	if err := transformer.Prewarm(context.Background(), ociRequest(address, settings)); err != nil {
+		t.Fatal(err)
+	}
+	reg.Close()
+
+	settings.IndexTTL = -1
+	result, err := (&transformer.Helm{}).Transform(context.Background(), ociRequest(address, settings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != "1.0.0" {
+		t.Errorf("Transform rendered the version %s, want 1.0.0", result.Version)
+	}
+}
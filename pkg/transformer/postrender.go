@@ -0,0 +1,268 @@
+package transformer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/postrender"
+	"k8s.io/cli-runtime/pkg/kustomize/k8sdeps"
+	"sigs.k8s.io/kustomize/pkg/fs"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/image"
+	"sigs.k8s.io/kustomize/pkg/loader"
+	"sigs.k8s.io/kustomize/pkg/patch"
+	"sigs.k8s.io/kustomize/pkg/target"
+	"sigs.k8s.io/kustomize/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// PostRender are the steps the manifest of a transform goes through after it
+// is rendered, the kustomization first and the post-renderers after it. The
+// hooks are left as they are.
+type PostRender struct {
+	// Kustomization is applied to the manifest in-process, nothing is applied
+	// when it is nil
+	Kustomization *Kustomization
+	// Renderers are the helm post-renderers the manifest is piped through in
+	// order, like postrender.NewExec which runs an external binary
+	Renderers []postrender.PostRenderer
+}
+
+// Kustomization is the kustomize overlay of the manifest, the fields are the
+// ones of a kustomization.yaml with the patches held in memory
+type Kustomization struct {
+	// Namespace rewrites the namespace of the namespaced objects
+	Namespace string
+	// CommonLabels are added to the objects and to their selectors
+	CommonLabels map[string]string
+	// CommonAnnotations are added to the objects
+	CommonAnnotations map[string]string
+	// Images override the names, the tags and the digests of the images
+	Images []Image
+	// PatchesStrategicMerge are the strategic merge patches in YAML, each one
+	// names the object it patches
+	PatchesStrategicMerge []string
+	// PatchesJSON6902 are the JSON patches of single objects
+	PatchesJSON6902 []JSONPatch
+}
+
+// Image is the override of an image, the name is the image name without a tag
+type Image struct {
+	Name    string
+	NewName string
+	NewTag  string
+	// Digest wins over the new tag
+	Digest string
+}
+
+// JSONPatch is a JSON patch, RFC 6902, of the object of the target
+type JSONPatch struct {
+	Target PatchTarget
+	// Patch are the operations in JSON or YAML
+	Patch string
+}
+
+// PatchTarget names the object of a patch by its name and namespace in the
+// rendered manifest, before the kustomization moves it to its namespace
+type PatchTarget struct {
+	Group     string
+	Version   string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// The in-memory kustomization of the manifest
+const (
+	kustomizeDir = "/meshinfra"
+	// indexAnnotation carries the position of an object of the manifest
+	// through kustomize, which orders the objects by their ids
+	indexAnnotation = "meshinfra.io/index"
+)
+
+// postRender applies the post-render steps of the request to the manifest of
+// the result, the objects are parsed from the post-rendered manifest again
+func postRender(ctx context.Context, req *Request, result *Result) error {
+	pr := req.PostRender
+	if pr.Kustomization == nil && len(pr.Renderers) == 0 {
+		return nil
+	}
+	logger := requestLogger(req)
+
+	manifest := result.Manifest
+	err := runContext(ctx, func() error {
+		if pr.Kustomization != nil {
+			objects, err := ParseManifest(manifest)
+			if err != nil {
+				return err
+			}
+			logger.Debugf("Applying the kustomization to %d objects", len(objects))
+			m, err := kustomize(pr.Kustomization, objects)
+			if err != nil {
+				return errors.Wrap(err, "failed applying the kustomization")
+			}
+			manifest = m
+		}
+		for i, r := range pr.Renderers {
+			out, err := r.Run(bytes.NewBufferString(manifest))
+			if err != nil {
+				return errors.Wrapf(err, "failed running the post-renderer %d", i+1)
+			}
+			manifest = out.String()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	objects, err := ParseManifest(manifest)
+	if err != nil {
+		return errors.Wrap(err, "failed parsing the post-rendered manifest")
+	}
+	result.Manifest = manifest
+	result.Objects = objects
+	if result.Release != nil {
+		result.Release.Manifest = manifest
+	}
+	return nil
+}
+
+// kustomize applies the kustomization to the objects and returns the manifest
+// of the objects in their order, with the chart templates they were rendered
+// from
+func kustomize(k *Kustomization, objects []Object) (string, error) {
+	fSys := fs.MakeFakeFS()
+	kust := &types.Kustomization{
+		Resources:         []string{"manifest.yaml"},
+		Namespace:         k.Namespace,
+		CommonLabels:      k.CommonLabels,
+		CommonAnnotations: k.CommonAnnotations,
+	}
+	for _, img := range k.Images {
+		kust.Images = append(kust.Images, image.Image{Name: img.Name, NewName: img.NewName, NewTag: img.NewTag, Digest: img.Digest})
+	}
+	for i, p := range k.PatchesStrategicMerge {
+		name := fmt.Sprintf("patch-%d.yaml", i)
+		if err := fSys.WriteFile(path.Join(kustomizeDir, name), []byte(p)); err != nil {
+			return "", err
+		}
+		kust.PatchesStrategicMerge = append(kust.PatchesStrategicMerge, patch.StrategicMerge(name))
+	}
+	for i, p := range k.PatchesJSON6902 {
+		name := fmt.Sprintf("json-patch-%d.yaml", i)
+		if err := fSys.WriteFile(path.Join(kustomizeDir, name), []byte(p.Patch)); err != nil {
+			return "", err
+		}
+		// kustomize patches the objects once they are moved to the namespace
+		t := p.Target
+		if t.Namespace != "" && k.Namespace != "" {
+			t.Namespace = k.Namespace
+		}
+		kust.PatchesJson6902 = append(kust.PatchesJson6902, patch.Json6902{
+			Target: &patch.Target{Gvk: gvk.Gvk{Group: t.Group, Version: t.Version, Kind: t.Kind}, Namespace: t.Namespace, Name: t.Name},
+			Path:   name,
+		})
+	}
+	data, err := yaml.Marshal(kust)
+	if err != nil {
+		return "", err
+	}
+	if err := fSys.WriteFile(path.Join(kustomizeDir, "kustomization.yaml"), data); err != nil {
+		return "", err
+	}
+
+	docs := make([]string, 0, len(objects))
+	for i, obj := range objects {
+		doc, err := annotate(obj.Raw, strconv.Itoa(i))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed parsing the object rendered from %q", obj.Source)
+		}
+		docs = append(docs, doc)
+	}
+	if err := fSys.WriteFile(path.Join(kustomizeDir, "manifest.yaml"), []byte(strings.Join(docs, "---\n"))); err != nil {
+		return "", err
+	}
+
+	f := k8sdeps.NewFactory()
+	ldr, err := loader.NewLoader(kustomizeDir, fSys)
+	if err != nil {
+		return "", err
+	}
+	defer ldr.Cleanup()
+	kt, err := target.NewKustTarget(ldr, f.ResmapF, f.TransformerF)
+	if err != nil {
+		return "", err
+	}
+	resources, err := kt.MakeCustomizedResMap()
+	if err != nil {
+		return "", err
+	}
+
+	// The objects are put back in their order, an object without an index
+	// comes last
+	type indexed struct {
+		index int
+		doc   []byte
+	}
+	out := make([]indexed, 0, len(resources))
+	for _, res := range resources {
+		index := len(objects)
+		annotations := res.GetAnnotations()
+		if i, err := strconv.Atoi(annotations[indexAnnotation]); err == nil && i < len(objects) {
+			index = i
+		}
+		delete(annotations, indexAnnotation)
+		res.SetAnnotations(annotations)
+		if len(annotations) == 0 {
+			if metadata, ok := res.Map()["metadata"].(map[string]interface{}); ok {
+				delete(metadata, "annotations")
+			}
+		}
+
+		doc, err := yaml.Marshal(res.Map())
+		if err != nil {
+			return "", err
+		}
+		out = append(out, indexed{index, doc})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].index < out[j].index })
+
+	var b strings.Builder
+	for _, o := range out {
+		b.WriteString("---\n")
+		if o.index < len(objects) && objects[o.index].Source != "" {
+			b.WriteString(sourcePrefix + objects[o.index].Source + "\n")
+		}
+		b.Write(o.doc)
+	}
+	return b.String(), nil
+}
+
+// annotate returns the YAML of the object with the index annotation
+func annotate(raw, index string) (string, error) {
+	var obj map[string]interface{}
+	if err := yaml.Unmarshal([]byte(raw), &obj); err != nil {
+		return "", err
+	}
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = map[string]interface{}{}
+		obj["metadata"] = metadata
+	}
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		annotations = map[string]interface{}{}
+		metadata["annotations"] = annotations
+	}
+	annotations[indexAnnotation] = index
+
+	data, err := yaml.Marshal(obj)
+	return string(data), err
+}
@@ -0,0 +1,153 @@
+package transformer
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/postrender"
+)
+
+// renderMesh renders the test chart for the post-render tests
+func renderMesh(t *testing.T) (*Request, *Result) {
+	req := &Request{ReleaseName: "mesh", ChartPath: "testdata/mesh", Namespace: "mesh"}
+	result, err := (&Helm{}).Transform(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req, result
+}
+
+func TestPostRenderKustomization(t *testing.T) {
+	req, result := renderMesh(t)
+	req.PostRender.Kustomization = &Kustomization{
+		Namespace:         "mesh-system",
+		CommonLabels:      map[string]string{"team": "mesh"},
+		CommonAnnotations: map[string]string{"owner": "meshery"},
+		Images:            []Image{{Name: "mesh/controller", NewName: "registry.local/controller", NewTag: "v2"}},
+		PatchesStrategicMerge: []string{`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: mesh-controller
+  namespace: mesh
+spec:
+  replicas: 3
+`},
+		PatchesJSON6902: []JSONPatch{{
+			Target: PatchTarget{Version: "v1", Kind: "ConfigMap", Namespace: "mesh", Name: "mesh-config"},
+			Patch:  `[{"op": "add", "path": "/data/patched", "value": "true"}]`,
+		}},
+	}
+
+	if err := postRender(context.Background(), req, result); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Objects) != 2 || result.Objects[0].Kind != "ConfigMap" || result.Objects[1].Kind != "Deployment" {
+		t.Fatalf("the objects %v are not kept in their order", result.Objects)
+	}
+	if src := result.Objects[1].Source; src != "mesh/templates/deployment.yaml" {
+		t.Errorf("the deployment is rendered from %q", src)
+	}
+	for _, obj := range result.Objects {
+		if obj.Namespace != "mesh-system" {
+			t.Errorf("the %s is in the namespace %q", obj.Kind, obj.Namespace)
+		}
+	}
+	for _, want := range []string{"team: mesh", "owner: meshery", "image: registry.local/controller:v2", "replicas: 3", `patched: "true"`} {
+		if !strings.Contains(result.Manifest, want) {
+			t.Errorf("manifest does not contain %q:\n%s", want, result.Manifest)
+		}
+	}
+	if strings.Contains(result.Manifest, indexAnnotation) {
+		t.Errorf("the index annotation is left in the manifest:\n%s", result.Manifest)
+	}
+	if result.Release.Manifest != result.Manifest {
+		t.Error("the manifest of the release is not post-rendered")
+	}
+	if len(result.Hooks) != 1 || strings.Contains(result.Hooks[0].Manifest, "mesh-system") {
+		t.Errorf("the hooks are post-rendered: %v", result.Hooks)
+	}
+}
+
+// renderFunc is a post-renderer of a function
+type renderFunc func(string) (string, error)
+
+func (f renderFunc) Run(in *bytes.Buffer) (*bytes.Buffer, error) {
+	out, err := f(in.String())
+	return bytes.NewBufferString(out), err
+}
+
+func TestPostRenderRenderers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "post-render.sh")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\nsed 's/replicas: 3/replicas: 5/'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	exec, err := postrender.NewExec(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, result := renderMesh(t)
+	req.PostRender = PostRender{
+		Kustomization: &Kustomization{CommonLabels: map[string]string{"team": "mesh"}},
+		Renderers: []postrender.PostRenderer{
+			renderFunc(func(in string) (string, error) {
+				if !strings.Contains(in, "team: mesh") {
+					t.Error("the renderers run before the kustomization")
+				}
+				return strings.Replace(in, "replicas: 1", "replicas: 3", 1), nil
+			}),
+			exec,
+		},
+	}
+
+	if err := postRender(context.Background(), req, result); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Manifest, "replicas: 5") || !strings.Contains(result.Objects[1].Raw, "replicas: 5") {
+		t.Errorf("the renderers did not run in order:\n%s", result.Manifest)
+	}
+}
+
+func TestPostRenderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		pr   PostRender
+	}{
+		{"unknown patch target", PostRender{Kustomization: &Kustomization{
+			PatchesJSON6902: []JSONPatch{{Target: PatchTarget{Version: "v1", Kind: "ConfigMap", Name: "unknown"}, Patch: `[{"op": "remove", "path": "/data"}]`}},
+		}}},
+		{"invalid patch", PostRender{Kustomization: &Kustomization{PatchesStrategicMerge: []string{"kind: ["}}}},
+		{"failed renderer", PostRender{Renderers: []postrender.PostRenderer{
+			renderFunc(func(string) (string, error) { return "", os.ErrInvalid }),
+		}}},
+		{"invalid manifest", PostRender{Renderers: []postrender.PostRenderer{
+			renderFunc(func(string) (string, error) { return "kind: [", nil }),
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, result := renderMesh(t)
+			manifest := result.Manifest
+			req.PostRender = tt.pr
+			if err := postRender(context.Background(), req, result); err == nil {
+				t.Fatal("postRender should fail")
+			}
+			if result.Manifest != manifest {
+				t.Error("the manifest of a failed post-render is changed")
+			}
+		})
+	}
+}
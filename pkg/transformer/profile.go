@@ -0,0 +1,68 @@
+package transformer
+
+import (
+	"sort"
+	"strings"
+
+	common "github.com/Aisuko/meshinfra/pkg/common"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// ProfileHa is the profile of the values-ha.yaml file many mesh charts ship
+const ProfileHa = "ha"
+
+// ProfileFile returns the name of the values file of the profile, "prod" is
+// the values-prod.yaml file and a name with a yaml extension is the file itself
+func ProfileFile(profile string) string {
+	if strings.HasSuffix(profile, ".yaml") || strings.HasSuffix(profile, ".yml") {
+		return profile
+	}
+	return "values-" + profile + ".yaml"
+}
+
+// profileValues returns the merged values of the profile files of the chart,
+// in the order of the profiles, and the names of the files applied
+func profileValues(ch *chart.Chart, profiles []string) (map[string]interface{}, []string, error) {
+	vals := map[string]interface{}{}
+	var files []string
+	for _, profile := range profiles {
+		name := ProfileFile(profile)
+		file := chartFile(ch, name)
+		if file == nil {
+			return nil, nil, errors.Errorf("profile %q not found, the chart %s has no %s file (available profiles: %s)",
+				profile, ch.Name(), name, strings.Join(chartProfiles(ch), ", "))
+		}
+
+		current, err := chartutil.ReadValues(file.Data)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed parsing the %s file of the chart %s", name, ch.Name())
+		}
+		vals = common.MergeMaps(vals, current.AsMap())
+		files = append(files, name)
+	}
+	return vals, files, nil
+}
+
+// chartFile returns the file of the chart, nil when it has none by the name
+func chartFile(ch *chart.Chart, name string) *chart.File {
+	for _, f := range ch.Files {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// chartProfiles returns the profiles of the values files the chart ships
+func chartProfiles(ch *chart.Chart) []string {
+	var profiles []string
+	for _, f := range ch.Files {
+		if strings.HasPrefix(f.Name, "values-") && strings.HasSuffix(f.Name, ".yaml") && !strings.Contains(f.Name, "/") {
+			profiles = append(profiles, strings.TrimSuffix(strings.TrimPrefix(f.Name, "values-"), ".yaml"))
+		}
+	}
+	sort.Strings(profiles)
+	return profiles
+}
@@ -0,0 +1,269 @@
+// Package registrytest provides an in-process OCI registry serving charts, it
+// stands in for a registry in the tests of oci:// chart sources:
+//
+//	reg := registrytest.New()
+//	defer reg.Close()
+//	err := reg.Push("meshes", ch)
+//
+//	req.ChartRepoAddress = reg.Address("meshes")
+//	req.Registry.PlainHTTP = true
+package registrytest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// The media types of the chart artifacts the registry serves
+const (
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ConfigMediaType   = "application/vnd.cncf.helm.config.v1+json"
+	ChartMediaType    = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	CosignMediaType   = "application/vnd.dev.cosign.simplesigning.v1+json"
+)
+
+// token is the bearer token the registry hands out for the credentials
+const token = "registrytest-token"
+
+// Registry is an in-process OCI registry speaking the pull part of the
+// distribution API over plain http
+type Registry struct {
+	*httptest.Server
+
+	username string
+	password string
+
+	mu        sync.Mutex
+	manifests map[string]map[string][]byte
+	blobs     map[string][]byte
+	requests  map[string]int
+	// credentials are the ones the token was asked for with
+	credentials []string
+}
+
+// New starts a registry which serves everybody
+func New() *Registry {
+	return NewWithAuth("", "")
+}
+
+// NewWithAuth starts a registry which serves the holders of a bearer token, it
+// hands the token out for the credentials like the docker token auth
+func NewWithAuth(username, password string) *Registry {
+	r := &Registry{
+		username:  username,
+		password:  password,
+		manifests: map[string]map[string][]byte{},
+		blobs:     map[string][]byte{},
+		requests:  map[string]int{},
+	}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+	return r
+}
+
+// Host returns the host of the registry
+func (r *Registry) Host() string {
+	return strings.TrimPrefix(r.URL, "http://")
+}
+
+// Address returns the oci:// address of the namespace of the registry
+func (r *Registry) Address(namespace string) string {
+	return "oci://" + r.Host() + "/" + namespace
+}
+
+// Push packages the chart and stores it in the namespace, the repository is
+// named after the chart and the chart version is the tag
+func (r *Registry) Push(namespace string, ch *chart.Chart) error {
+	dir, err := ioutil.TempDir("", "registrytest")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	archive, err := chartutil.Save(ch, dir)
+	if err != nil {
+		return err
+	}
+	layer, err := ioutil.ReadFile(archive)
+	if err != nil {
+		return err
+	}
+	config, err := json.Marshal(ch.Metadata)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"config":        r.descriptor(ConfigMediaType, config),
+		"layers":        []interface{}{r.descriptor(ChartMediaType, layer)},
+	})
+	if err != nil {
+		return err
+	}
+
+	repository := namespace + "/" + ch.Name()
+	if r.manifests[repository] == nil {
+		r.manifests[repository] = map[string][]byte{}
+	}
+	r.manifests[repository][strings.Replace(ch.Metadata.Version, "+", "_", -1)] = manifest
+	return nil
+}
+
+// Sign stores a cosign signature of the chart version of the namespace made
+// with the key
+func (r *Registry) Sign(namespace, name, version string, key *ecdsa.PrivateKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	repository := namespace + "/" + name
+	manifest, ok := r.manifests[repository][strings.Replace(version, "+", "_", -1)]
+	if !ok {
+		return fmt.Errorf("the chart %s %s is not pushed", repository, version)
+	}
+	sum := sha256.Sum256(manifest)
+	digest := hex.EncodeToString(sum[:])
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"critical": map[string]interface{}{
+			"identity": map[string]string{"docker-reference": r.Host() + "/" + repository},
+			"image":    map[string]string{"docker-manifest-digest": "sha256:" + digest},
+			"type":     "cosign container image signature",
+		},
+		"optional": nil,
+	})
+	if err != nil {
+		return err
+	}
+	payloadSum := sha256.Sum256(payload)
+	sig, err := key.Sign(rand.Reader, payloadSum[:], crypto.SHA256)
+	if err != nil {
+		return err
+	}
+
+	layer := r.descriptor(CosignMediaType, payload)
+	layer["annotations"] = map[string]string{
+		"dev.cosignproject.cosign/signature": base64.StdEncoding.EncodeToString(sig),
+	}
+	signature, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"config":        r.descriptor("application/vnd.oci.image.config.v1+json", []byte("{}")),
+		"layers":        []interface{}{layer},
+	})
+	if err != nil {
+		return err
+	}
+	r.manifests[repository]["sha256-"+digest+".sig"] = signature
+	return nil
+}
+
+// Requests returns how many times the path was requested
+func (r *Registry) Requests(path string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests[path]
+}
+
+// Credentials returns the "username:password" credentials the token was asked
+// for with, in order
+func (r *Registry) Credentials() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.credentials...)
+}
+
+// descriptor stores the blob and returns its descriptor
+func (r *Registry) descriptor(mediaType string, data []byte) map[string]interface{} {
+	sum := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	r.blobs[digest] = data
+	return map[string]interface{}{
+		"mediaType": mediaType,
+		"digest":    digest,
+		"size":      len(data),
+	}
+}
+
+func (r *Registry) serve(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests[req.URL.Path]++
+
+	if req.URL.Path == "/token" {
+		r.serveToken(w, req)
+		return
+	}
+	if r.username != "" && req.Header.Get("Authorization") != "Bearer "+token {
+		scope := strings.TrimPrefix(req.URL.Path, "/v2/")
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registrytest",scope="repository:%s:pull"`, r.URL, scope))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case strings.HasSuffix(path, "/tags/list"):
+		tags, ok := r.manifests[strings.TrimSuffix(path, "/tags/list")]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		list := struct {
+			Tags []string `json:"tags"`
+		}{Tags: []string{}}
+		for tag := range tags {
+			list.Tags = append(list.Tags, tag)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	case strings.Contains(path, "/manifests/"):
+		i := strings.LastIndex(path, "/manifests/")
+		manifest, ok := r.manifests[path[:i]][path[i+len("/manifests/"):]]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", ManifestMediaType)
+		_, _ = w.Write(manifest)
+	case strings.Contains(path, "/blobs/"):
+		blob, ok := r.blobs[path[strings.LastIndex(path, "/")+1:]]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write(blob)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// serveToken hands the token out for the credentials of the registry
+func (r *Registry) serveToken(w http.ResponseWriter, req *http.Request) {
+	username, password, ok := req.BasicAuth()
+	if ok {
+		r.credentials = append(r.credentials, username+":"+password)
+	}
+	if !ok || username != r.username || password != r.password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
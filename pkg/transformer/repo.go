@@ -0,0 +1,263 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	util "github.com/Aisuko/meshinfra/pkg/ioutil"
+	"github.com/gofrs/flock"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+// RepoError is the failure of updating a single chart repo
+type RepoError struct {
+	Name string
+	URL  string
+	Err  error
+}
+
+func (e *RepoError) Error() string {
+	return fmt.Sprintf("update the %s chart repo (%s) failed: %s", e.Name, e.URL, e.Err)
+}
+
+// Cause returns the underlying error of the failure
+func (e *RepoError) Cause() error {
+	return e.Err
+}
+
+// RepoErrors aggregates the failures of updating the chart repos
+type RepoErrors []*RepoError
+
+func (e RepoErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d chart repo(s) failed to update: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// repoFile is the repo config. Its entries keep the TLS and the credential
+// settings newer helm releases write, which the repo.Entry of helm 3.1 drops.
+type repoFile struct {
+	APIVersion   string       `json:"apiVersion"`
+	Generated    time.Time    `json:"generated"`
+	Repositories []*repoEntry `json:"repositories"`
+}
+
+// repoEntry is a chart repo of the repo config
+type repoEntry struct {
+	repo.Entry
+	InsecureSkipTLSVerify bool `json:"insecure_skip_tls_verify,omitempty"`
+	PassCredentialsAll    bool `json:"pass_credentials_all,omitempty"`
+}
+
+// options returns the options of the chart repo, the ones held in memory are
+// only the ones of a request
+func (e *repoEntry) options() RepoOptions {
+	return RepoOptions{
+		Username:              e.Username,
+		Password:              e.Password,
+		PassCredentialsAll:    e.PassCredentialsAll,
+		CAFile:                e.CAFile,
+		CertFile:              e.CertFile,
+		KeyFile:               e.KeyFile,
+		InsecureSkipTLSVerify: e.InsecureSkipTLSVerify,
+	}
+}
+
+// loadRepoFile loads the repo config of the path, a missing one has no repos
+func loadRepoFile(path string) (*repoFile, error) {
+	f := &repoFile{APIVersion: repo.APIVersionV1}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, errors.Wrapf(err, "failed parsing the repo config %s", path)
+	}
+	return f, nil
+}
+
+// get returns the repo of the name, nil when the repo config has none
+func (f *repoFile) get(name string) *repoEntry {
+	for _, entry := range f.Repositories {
+		if entry.Name == name {
+			return entry
+		}
+	}
+	return nil
+}
+
+// update adds the repo or replaces the one of the same name
+func (f *repoFile) update(entry *repoEntry) {
+	for i, e := range f.Repositories {
+		if e.Name == entry.Name {
+			f.Repositories[i] = entry
+			return
+		}
+	}
+	f.Repositories = append(f.Repositories, entry)
+}
+
+// write writes the repo config to the path
+func (f *repoFile) write(path string) error {
+	f.Generated = time.Now()
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// addRepo is used to add the chart repo address to the repo config
+func addRepo(ctx context.Context, settings *cli.EnvSettings, req *Request) (err error) {
+	logger := requestLogger(req)
+	repoFile := settings.RepositoryConfig
+
+	//Ensure the file directory exists as it is required for file locking
+	err = os.MkdirAll(filepath.Dir(repoFile), os.ModePerm)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	fileLock := flock.New(strings.Replace(repoFile, filepath.Ext(repoFile), ".lock", 1))
+	lockCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+
+	defer cancel()
+	locked, err := fileLock.TryLockContext(lockCtx, time.Second)
+	if err == nil && locked {
+		defer util.SafeUnLock(fileLock, &err)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	f, err := loadRepoFile(filepath.Clean(repoFile))
+	if err != nil {
+		return err
+	}
+
+	if f.get(req.RepoName) != nil {
+		logger.Debugf("Repository name %s already exists", req.RepoName)
+	}
+
+	// The credentials of the request are kept out of the repo config
+	entry := req.Repo.entry(req.RepoName, req.ChartRepoAddress)
+
+	if err := refreshIndex(ctx, settings, req, entry); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		err := errors.Wrapf(err, "looks like %q is not a valid chart repository or cannot be reached", req.ChartRepoAddress)
+		return err
+	}
+
+	f.update(entry)
+
+	if err := f.write(repoFile); err != nil {
+		logger.Errorf("Add the %s chart repo failed: %s", req.RepoName, err)
+		return err
+	}
+
+	return nil
+}
+
+// updateRepo is used to update all the chart repos, the failures of all the
+// repos are returned together as RepoErrors. With IgnoreUnrelatedRepoErrors
+// only the failure of the chart repo of the request is returned. The downloads
+// are bounded by the repo concurrency and the repo timeout of the settings, a
+// download which times out is cancelled and frees its slot once it stopped.
+func updateRepo(ctx context.Context, settings *cli.EnvSettings, req *Request) error {
+	logger := requestLogger(req)
+	f, err := loadRepoFile(settings.RepositoryConfig)
+	if err != nil {
+		return err
+	}
+	if len(f.Repositories) == 0 {
+		return errors.New("no repositories found. You must add one before updating")
+	}
+
+	var (
+		mu   sync.Mutex
+		errs RepoErrors
+		wg   sync.WaitGroup
+	)
+	fail := func(cfg *repoEntry, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, &RepoError{Name: cfg.Name, URL: cfg.URL, Err: err})
+	}
+
+	concurrency, timeout := req.Settings.RepoConcurrency, req.Settings.RepoTimeout
+	if concurrency <= 0 {
+		concurrency = DefaultRepoConcurrency
+	}
+	if timeout <= 0 {
+		timeout = DefaultRepoTimeout
+	}
+	sem := make(chan struct{}, concurrency)
+
+	for _, cfg := range f.Repositories {
+		wg.Add(1)
+		go func(cfg *repoEntry) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			// refreshIndex only returns once the download bound to rctx stopped
+			rctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			if err := refreshIndex(rctx, settings, req, cfg); err != nil {
+				fail(cfg, err)
+			}
+		}(cfg)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	errs = filterRepoErrors(req, errs)
+	if len(errs) > 0 {
+		logger.Errorf("Update %s repo index failed", req.RepoName)
+		return errs
+	}
+
+	logger.Debugf("Update %s repo index succeed", req.RepoName)
+	return nil
+}
+
+// filterRepoErrors sorts the failures by repo name and drops the ones of the
+// repos unrelated to the request when they are tolerated
+func filterRepoErrors(req *Request, errs RepoErrors) RepoErrors {
+	var filtered RepoErrors
+	for _, err := range errs {
+		if req.IgnoreUnrelatedRepoErrors && err.Name != req.RepoName {
+			requestLogger(req).Warnf("Ignore the failure of the unrelated %s repo: %s", err.Name, err.Err)
+			continue
+		}
+		filtered = append(filtered, err)
+	}
+	sort.Slice(filtered, func(i, j int) bool { return filtered[i].Name < filtered[j].Name })
+	return filtered
+}
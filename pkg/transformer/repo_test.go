@@ -0,0 +1,211 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// withRepoConfig returns the helm settings of a temporary repo config holding
+// the entries and the func removing it when the test is done
+func withRepoConfig(t *testing.T, entries ...*repo.Entry) (*cli.EnvSettings, func()) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := repo.NewFile()
+	for _, entry := range entries {
+		f.Update(entry)
+	}
+	repoFile := filepath.Join(dir, "repositories.yaml")
+	if err := f.WriteFile(repoFile, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := envSettings(&Request{Settings: Settings{
+		RepositoryConfig: repoFile,
+		RepositoryCache:  filepath.Join(dir, "repository"),
+	}})
+	return settings, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestUpdateRepo(t *testing.T) {
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("apiVersion: v1\nentries: {}\n"))
+	}))
+	defer good.Close()
+
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+
+	settings, cleanup := withRepoConfig(t,
+		&repo.Entry{Name: "good", URL: good.URL},
+		&repo.Entry{Name: "broken-b", URL: broken.URL},
+		&repo.Entry{Name: "broken-a", URL: broken.URL + "/charts"},
+	)
+	defer cleanup()
+
+	tests := []struct {
+		name     string
+		req      *Request
+		wantErrs []string
+	}{
+		{"all failures", &Request{RepoName: "good"}, []string{"broken-a", "broken-b"}},
+		{"unrelated failures ignored", &Request{RepoName: "good", IgnoreUnrelatedRepoErrors: true}, nil},
+		{"related failure", &Request{RepoName: "broken-b", IgnoreUnrelatedRepoErrors: true}, []string{"broken-b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := updateRepo(context.Background(), settings, tt.req)
+			if tt.wantErrs == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			errs, ok := err.(RepoErrors)
+			if !ok {
+				t.Fatalf("updateRepo returned %v, want RepoErrors", err)
+			}
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("updateRepo returned %d failures, want %d: %v", len(errs), len(tt.wantErrs), errs)
+			}
+			for i, name := range tt.wantErrs {
+				if errs[i].Name != name || errs[i].Err == nil {
+					t.Errorf("failure %d is %v, want one of the %s repo", i, errs[i], name)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateRepoNoRepositories(t *testing.T) {
+	settings, cleanup := withRepoConfig(t)
+	defer cleanup()
+
+	if err := updateRepo(context.Background(), settings, &Request{RepoName: "stable"}); err == nil {
+		t.Fatal("updateRepo without repositories should fail")
+	}
+}
+
+func TestUpdateRepoBounded(t *testing.T) {
+	var (
+		mu                sync.Mutex
+		inFlight, maxSeen int
+	)
+	gone := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/slow") {
+			select {
+			case <-r.Context().Done():
+				close(gone)
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+
+		mu.Lock()
+		inFlight++
+		if inFlight > maxSeen {
+			maxSeen = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("apiVersion: v1\nentries: {}\n"))
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	entries := []*repo.Entry{{Name: "slow", URL: srv.URL + "/slow"}}
+	for i := 0; i < 6; i++ {
+		entries = append(entries, &repo.Entry{Name: fmt.Sprintf("repo-%d", i), URL: fmt.Sprintf("%s/repo-%d", srv.URL, i)})
+	}
+	settings, cleanup := withRepoConfig(t, entries...)
+	defer cleanup()
+
+	err := updateRepo(context.Background(), settings, &Request{
+		RepoName: "repo-0",
+		Settings: Settings{RepoConcurrency: 2, RepoTimeout: 200 * time.Millisecond},
+	})
+	errs, ok := err.(RepoErrors)
+	if !ok || len(errs) != 1 || errs[0].Name != "slow" || errs[0].Err != context.DeadlineExceeded {
+		t.Fatalf("updateRepo returned %v, want the timeout of the slow repo", err)
+	}
+	if maxSeen > 2 {
+		t.Errorf("%d indexes were downloaded concurrently, want at most 2", maxSeen)
+	}
+	// The download of the slow repo is given up with its slot, not left running
+	select {
+	case <-gone:
+	case <-time.After(time.Second):
+		t.Error("the download of the slow repo was not cancelled")
+	}
+}
+
+func TestRepoFileEntrySettings(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("apiVersion: v1\nentries: {}\n"))
+	}))
+	defer srv.Close()
+
+	// The settings newer helm releases write to the entries
+	settings, cleanup := withRepoConfig(t)
+	defer cleanup()
+	config := fmt.Sprintf(`apiVersion: v1
+repositories:
+- name: insecure
+  url: %s
+  insecure_skip_tls_verify: true
+  pass_credentials_all: true
+`, srv.URL)
+	if err := ioutil.WriteFile(settings.RepositoryConfig, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := loadRepoFile(settings.RepositoryConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts := f.get("insecure").options(); !opts.InsecureSkipTLSVerify || !opts.PassCredentialsAll {
+		t.Errorf("the options of the entry are %+v, want the TLS verification skipped and the credentials passed to all hosts", opts)
+	}
+	if err := updateRepo(context.Background(), settings, &Request{RepoName: "other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Adding a repo keeps the settings of the other repos
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("apiVersion: v1\nentries: {}\n"))
+	}))
+	defer local.Close()
+	if err := addRepo(context.Background(), settings, &Request{RepoName: "local", ChartRepoAddress: local.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = loadRepoFile(settings.RepositoryConfig); err != nil {
+		t.Fatal(err)
+	}
+	if entry := f.get("insecure"); entry == nil || !entry.InsecureSkipTLSVerify || !entry.PassCredentialsAll {
+		t.Errorf("adding a repo dropped the settings of the insecure repo: %+v", entry)
+	}
+	if f.get("local") == nil {
+		t.Error("the local repo is missing in the repo config")
+	}
+}
@@ -0,0 +1,103 @@
+package transformer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// defaultNamespace is the namespace the chart is rendered into when the
+// request does not set one
+const defaultNamespace = "default"
+
+// The defaults of refreshing all the repos
+const (
+	DefaultRepoConcurrency = 4
+	DefaultRepoTimeout     = 30 * time.Second
+)
+
+// Settings is the helm environment of a single transform. Nothing is read from
+// or written to the process-wide helm environment, so transforms for different
+// tenants can run concurrently.
+type Settings struct {
+	// RepositoryConfig is the path to the repositories file
+	RepositoryConfig string
+	// RepositoryCache is the path to the cache directory of the repo indexes and charts
+	RepositoryCache string
+	// RegistryConfig is the path to the registry config file
+	RegistryConfig string
+	// IndexTTL is how long a cached repo index is used before it is refreshed,
+	// default is DefaultIndexTTL and a negative TTL always refreshes
+	IndexTTL time.Duration
+	// RepoConcurrency bounds the concurrent index downloads when all the repos
+	// are refreshed, default is DefaultRepoConcurrency
+	RepoConcurrency int
+	// RepoTimeout bounds the index download of every repo when all the repos
+	// are refreshed, the download is cancelled then, default is
+	// DefaultRepoTimeout
+	RepoTimeout time.Duration
+}
+
+// DefaultSettings returns the settings rooted in the meshinfra directories of
+// the user, they are used for every setting a request leaves empty
+func DefaultSettings() Settings {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		configDir = os.TempDir()
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+
+	return Settings{
+		RepositoryConfig: filepath.Join(configDir, "meshinfra", "repositories.yaml"),
+		RepositoryCache:  filepath.Join(cacheDir, "meshinfra", "repository"),
+		RegistryConfig:   filepath.Join(configDir, "meshinfra", "registry.json"),
+	}
+}
+
+// envSettings returns the helm settings of the request
+func envSettings(req *Request) *cli.EnvSettings {
+	s, defaults := req.Settings, DefaultSettings()
+	if s.RepositoryConfig == "" {
+		s.RepositoryConfig = defaults.RepositoryConfig
+	}
+	if s.RepositoryCache == "" {
+		s.RepositoryCache = defaults.RepositoryCache
+	}
+	if s.RegistryConfig == "" {
+		s.RegistryConfig = defaults.RegistryConfig
+	}
+
+	// The plugins directory is left empty, the helm plugins of the user are
+	// never run on behalf of a transform
+	return &cli.EnvSettings{
+		RepositoryConfig: s.RepositoryConfig,
+		RepositoryCache:  s.RepositoryCache,
+		RegistryConfig:   s.RegistryConfig,
+	}
+}
+
+// newChartRepository returns the chart repo of the entry which caches its
+// index in the repo cache of the settings and downloads it with the options
+func newChartRepository(ctx context.Context, settings *cli.EnvSettings, entry *repo.Entry, opts RepoOptions) (*repo.ChartRepository, error) {
+	r, err := repo.NewChartRepository(entry, repoProviders(ctx, settings, entry.URL, opts))
+	if err != nil {
+		return nil, err
+	}
+	r.CachePath = settings.RepositoryCache
+	return r, nil
+}
+
+// namespace returns the namespace of the request
+func namespace(req *Request) string {
+	if req.Namespace != "" {
+		return req.Namespace
+	}
+	return defaultNamespace
+}
@@ -0,0 +1,175 @@
+// Package transformer is the single entry point used to transform the chart of
+// a service mesh to kubernetes manifest.
+//
+// Every mesh package registers its Transformer under the mesh name in init, so
+// the caller only needs to import the mesh packages it wants to use:
+//
+//	import _ "github.com/Aisuko/meshinfra/pkg/linkerd"
+//
+//	result, err := transformer.Transform(ctx, "linkerd", &transformer.Request{...})
+package transformer
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/Aisuko/meshinfra/pkg/log"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Request describes the chart which should be transformed
+type Request struct {
+	ChartName   string
+	ReleaseName string
+	// Namespace is the namespace the chart is rendered into, default is "default"
+	Namespace string
+	RepoName  string
+	// ChartRepoAddress is the URL of the chart repo, or an oci:// address of
+	// the registry the chart is pulled from, see OCIScheme
+	ChartRepoAddress string
+	// Repo are the credentials and the TLS settings of the chart repo, the TLS
+	// settings apply to the registry of an oci:// address as well
+	Repo RepoOptions
+	// Verify are the options of verifying the integrity of the chart
+	Verify VerifyOptions
+	// Registry are the options of the registry of an oci:// chart repo address
+	Registry RegistryOptions
+	// ChartPath is a local chart directory or a packaged .tgz chart, the chart
+	// repo is not used when it is set
+	ChartPath string
+	// Chart is an already loaded chart, it takes precedence over ChartPath and
+	// the chart repo
+	Chart *chart.Chart
+	// Values are the value overrides of the chart, they win over the chart
+	// defaults, the profiles and the mesh values, see common.MergeValues
+	Values Values
+	// Version is the exact version or the semver range of the chart, default
+	// is the latest stable version. The version of a local or an in-memory
+	// chart is checked against it.
+	Version string
+	// Devel includes the prerelease versions of the chart when the version is
+	// empty, a version only matches a prerelease when it names one
+	Devel bool
+	IsHa  bool
+	// Profiles select values files shipped in the chart, see ProfileFile, they
+	// are applied in order over the chart values and below everything else.
+	// A profile the chart does not ship is an error.
+	Profiles []string
+	// RefreshAllRepos refreshes the index of every repo of the repo config, by
+	// default only the index of the chart repo of the request is refreshed
+	RefreshAllRepos bool
+	// IgnoreUnrelatedRepoErrors tolerates the failures of refreshing the chart
+	// repos other than the one of the request
+	IgnoreUnrelatedRepoErrors bool
+	// Settings is the helm environment of the transform
+	Settings Settings
+	// PostRender are the steps applied to the manifest after it is rendered,
+	// like a kustomization, Transform applies them to the manifest of every
+	// mesh
+	PostRender PostRender
+	// Options are the mesh specific options, their type is defined by the
+	// package of the mesh
+	Options interface{}
+	// Logger receives the log of the transform, nothing is logged when it is nil
+	Logger log.Logger
+}
+
+// Result is the outcome of the transforming
+type Result struct {
+	// Manifest is the rendered kubernetes manifest
+	Manifest string
+	// Objects are the kubernetes objects of the manifest, in the same order
+	Objects []Object
+	// Hooks are the chart hooks, they are not part of the manifest
+	Hooks []*release.Hook
+	// Release is the dry-run release the manifest was rendered from, it is nil
+	// when the manifest was rendered from several charts
+	Release *release.Release
+	// Releases are the dry-run releases of the charts when the manifest was
+	// rendered from several charts, their manifests are not post-rendered
+	Releases []*release.Release
+	// Profiles are the chart values files applied by the profiles of the request
+	Profiles []string
+	// Version is the version of the rendered chart
+	Version string
+	// Lock are the resolved versions of the dependencies of the chart, like the
+	// Chart.lock file, it is nil when the chart has no dependencies. The lock
+	// of several charts holds the dependencies of all of them.
+	Lock *chart.Lock
+	// Output is the mesh specific output of the transform, like the identity
+	// certificates generated by the linkerd transformer
+	Output interface{}
+}
+
+var (
+	mu           sync.RWMutex
+	transformers = make(map[string]Transformer)
+)
+
+// Register makes a transformer available by the mesh name, it panics if the
+// transformer is nil or the mesh name was registered twice
+func Register(mesh string, t Transformer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if t == nil {
+		panic("transformer: Register transformer is nil")
+	}
+	if _, dup := transformers[mesh]; dup {
+		panic("transformer: Register called twice for mesh " + mesh)
+	}
+	transformers[mesh] = t
+}
+
+// Get returns the transformer registered for the mesh
+func Get(mesh string) (Transformer, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	t, ok := transformers[mesh]
+	if !ok {
+		return nil, errors.Errorf("unknown mesh %q (forgotten import?)", mesh)
+	}
+	return t, nil
+}
+
+// Meshes returns the sorted names of the registered meshes
+func Meshes() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	meshes := make([]string, 0, len(transformers))
+	for mesh := range transformers {
+		meshes = append(meshes, mesh)
+	}
+	sort.Strings(meshes)
+	return meshes
+}
+
+// Transform is used to transform the chart of the mesh to kubernetes manifest,
+// the error is the one of the context when it is done before the transform.
+// The manifest is post-rendered once the transformer of the mesh rendered it
+// in full, so a patch may target the objects of any chart of the mesh.
+func Transform(ctx context.Context, mesh string, req *Request) (*Result, error) {
+	t, err := Get(mesh)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Logger != nil {
+		mreq := *req
+		mreq.Logger = req.Logger.WithFields(log.Fields{log.FieldMesh: mesh})
+		req = &mreq
+	}
+	result, err := t.Transform(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := postRender(ctx, req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
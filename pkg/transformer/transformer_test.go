@@ -0,0 +1,111 @@
+package transformer_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/log"
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	mock_transformer "github.com/Aisuko/meshinfra/pkg/transformer/mocks"
+	"github.com/golang/mock/gomock"
+)
+
+func TestTransform(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	req := &transformer.Request{ChartName: "mock", ReleaseName: "mock"}
+	want := &transformer.Result{Manifest: "kind: ConfigMap"}
+
+	mock := mock_transformer.NewMockTransformer(ctrl)
+	mock.EXPECT().Transform(context.Background(), req).Return(want, nil)
+	transformer.Register("mock", mock)
+
+	got, err := transformer.Transform(context.Background(), "mock", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Transform returned %v, want %v", got, want)
+	}
+
+	if meshes := transformer.Meshes(); !reflect.DeepEqual(meshes, []string{"mock"}) {
+		t.Errorf("Meshes returned %v", meshes)
+	}
+
+	if _, err := transformer.Transform(context.Background(), "unknown", req); err == nil {
+		t.Error("Transform of an unknown mesh should fail")
+	}
+
+	logger := &recordLogger{}
+	mock.EXPECT().Transform(context.Background(), gomock.Any()).Do(func(_ context.Context, req *transformer.Request) {
+		req.Logger.Infof("rendering")
+	}).Return(want, nil)
+	if _, err := transformer.Transform(context.Background(), "mock", &transformer.Request{Logger: logger}); err != nil {
+		t.Fatal(err)
+	}
+	if len(logger.entries) != 1 || logger.entries[0].fields[log.FieldMesh] != "mock" {
+		t.Errorf("the entries %v are not logged with the mock mesh", logger.entries)
+	}
+
+	rendered := &transformer.Result{Manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: mock\n"}
+	mock.EXPECT().Transform(context.Background(), gomock.Any()).Return(rendered, nil)
+	req = &transformer.Request{PostRender: transformer.PostRender{
+		Kustomization: &transformer.Kustomization{CommonLabels: map[string]string{"mesh": "mock"}},
+	}}
+	got, err = transformer.Transform(context.Background(), "mock", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Objects) != 1 || !strings.Contains(got.Objects[0].Raw, "mesh: mock") {
+		t.Errorf("Transform did not post-render the manifest:\n%s", got.Manifest)
+	}
+}
+
+// recordLogger records the entries logged by the transforms
+type recordLogger struct {
+	mu      sync.Mutex
+	fields  log.Fields
+	entries []*logEntry
+	parent  *recordLogger
+}
+
+type logEntry struct {
+	level  string
+	msg    string
+	fields log.Fields
+}
+
+func (l *recordLogger) root() *recordLogger {
+	if l.parent != nil {
+		return l.parent.root()
+	}
+	return l
+}
+
+func (l *recordLogger) WithFields(fields log.Fields) log.Logger {
+	merged := log.Fields{}
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &recordLogger{fields: merged, parent: l.root()}
+}
+
+func (l *recordLogger) log(level, format string, args ...interface{}) {
+	root := l.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	root.entries = append(root.entries, &logEntry{level: level, msg: fmt.Sprintf(format, args...), fields: l.fields})
+}
+
+func (l *recordLogger) Debugf(format string, args ...interface{}) { l.log("debug", format, args...) }
+func (l *recordLogger) Infof(format string, args ...interface{})  { l.log("info", format, args...) }
+func (l *recordLogger) Warnf(format string, args ...interface{})  { l.log("warn", format, args...) }
+func (l *recordLogger) Errorf(format string, args ...interface{}) { l.log("error", format, args...) }
@@ -0,0 +1,113 @@
+// Package transformertest provides the table tests shared by the mesh
+// transformers, rendering a chart per case and checking the manifest:
+//
+//	transformertest.Run(t, kuma.Name, transformer.Request{ChartPath: "testdata/kuma"}, []transformertest.Case{
+//		{Name: "ha", IsHa: true, Want: []string{"replicas: 3"}},
+//	})
+//
+// CheckValues keeps the values a mesh sets on the value paths of its upstream
+// chart, the values.yaml of the test charts are the ones of the upstream
+// charts.
+package transformertest
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// Case is a render of a mesh chart and what its manifest holds
+type Case struct {
+	Name    string
+	Options interface{}
+	// Set are the --set value overrides of the render
+	Set  []string
+	IsHa bool
+	// Want and NotWant are the strings the manifest holds and does not hold
+	Want    []string
+	NotWant []string
+	// Err is set when the render fails
+	Err bool
+	// Check checks the result further, it can be nil
+	Check func(t *testing.T, result *transformer.Result)
+}
+
+// Run renders the chart of the request with the mesh for every case
+func Run(t *testing.T, mesh string, req transformer.Request, cases []Case) {
+	t.Helper()
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			creq := req
+			creq.Values.Set = tt.Set
+			creq.IsHa = tt.IsHa
+			creq.Options = tt.Options
+			result, err := transformer.Transform(context.Background(), mesh, &creq)
+			if tt.Err {
+				if err == nil {
+					t.Fatal("Transform should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range tt.Want {
+				if !strings.Contains(result.Manifest, want) {
+					t.Errorf("manifest does not contain %q:\n%s", want, result.Manifest)
+				}
+			}
+			for _, notWant := range tt.NotWant {
+				if strings.Contains(result.Manifest, notWant) {
+					t.Errorf("manifest should not contain %q:\n%s", notWant, result.Manifest)
+				}
+			}
+			if tt.Check != nil {
+				tt.Check(t, result)
+			}
+		})
+	}
+}
+
+// CheckValues fails when a value of vals has no default in the values of the
+// chart, so a mesh only sets the values its chart knows of
+func CheckValues(t *testing.T, chartPath string, vals map[string]interface{}) {
+	t.Helper()
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range missingPaths(ch.Values, vals, "") {
+		t.Errorf("the %s chart has no value %s", ch.Name(), path)
+	}
+}
+
+// missingPaths returns the sorted paths of the values which have no default
+func missingPaths(defaults, vals map[string]interface{}, prefix string) []string {
+	var missing []string
+	for k, v := range vals {
+		def, ok := defaults[k]
+		switch {
+		case !ok:
+			missing = append(missing, prefix+k)
+		case isMap(v) && isMap(def):
+			missing = append(missing, missingPaths(toMap(def), toMap(v), prefix+k+".")...)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func isMap(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
+
+func toMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
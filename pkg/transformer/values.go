@@ -0,0 +1,125 @@
+package transformer
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"sort"
+
+	common "github.com/Aisuko/meshinfra/pkg/common"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/strvals"
+	"sigs.k8s.io/yaml"
+)
+
+// Values are the value overrides of a request. They are applied with the same
+// precedence as by helm install, every step wins over the previous ones: the
+// values files in order, the raw values, Set, SetString, SetFile and SetFileData.
+type Values struct {
+	// ValueFiles are the paths or URLs of the values files (-f/--values)
+	ValueFiles []string
+	// Raw are the values given as a map
+	Raw map[string]interface{}
+	// Set are the key=value overrides (--set)
+	Set []string
+	// SetString are the key=value overrides whose values are always strings (--set-string)
+	SetString []string
+	// SetFile are the key=path overrides whose values are read from the path (--set-file)
+	SetFile []string
+	// SetFileData are the --set-file overrides held in memory, the key is the
+	// path of the value like "identity.issuer.tls.crtPEM" and the data is used
+	// as is, so it needs no escaping of commas or equal signs
+	SetFileData map[string][]byte
+}
+
+// MergeValues returns the values of the overrides of the request merged like
+// the transforms merge them, the values files of a URL are downloaded without
+// the repo credentials
+func MergeValues(ctx context.Context, req *Request) (map[string]interface{}, error) {
+	return req.Values.merge(repoProviders(ctx, envSettings(req), "", RepoOptions{}))
+}
+
+// merge returns the values of the overrides, the chart values are layered
+// below them by common.MergeValues and helm
+func (v *Values) merge(p getter.Providers) (map[string]interface{}, error) {
+	var layers []map[string]interface{}
+
+	for _, filePath := range v.ValueFiles {
+		currentMap := map[string]interface{}{}
+
+		bytes, err := readFile(filePath, p)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := yaml.Unmarshal(bytes, &currentMap); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", filePath)
+		}
+		layers = append(layers, currentMap)
+	}
+
+	// The layers are copied, so the raw values are never changed by the
+	// overrides below or the rendering
+	base := common.MergeValues(append(layers, v.Raw)...)
+
+	for _, value := range v.Set {
+		if err := strvals.ParseInto(value, base); err != nil {
+			return nil, errors.Wrap(err, "failed parsing --set data")
+		}
+	}
+
+	for _, value := range v.SetString {
+		if err := strvals.ParseIntoString(value, base); err != nil {
+			return nil, errors.Wrap(err, "failed parsing --set-string data")
+		}
+	}
+
+	for _, value := range v.SetFile {
+		reader := func(rs []rune) (interface{}, error) {
+			bytes, err := readFile(string(rs), p)
+			return string(bytes), err
+		}
+		if err := strvals.ParseIntoFile(value, base, reader); err != nil {
+			return nil, errors.Wrap(err, "failed parsing --set-file data")
+		}
+	}
+
+	keys := make([]string, 0, len(v.SetFileData))
+	for key := range v.SetFileData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		data := v.SetFileData[key]
+		reader := func([]rune) (interface{}, error) {
+			return string(data), nil
+		}
+		// The placeholder only marks the value, the reader returns the data
+		if err := strvals.ParseIntoFile(key+"=-", base, reader); err != nil {
+			return nil, errors.Wrapf(err, "failed parsing --set-file data of %q", key)
+		}
+	}
+
+	return base, nil
+}
+
+// readFile reads a local file, or a remote file when the path is an URL one of
+// the getters can handle
+func readFile(filePath string, p getter.Providers) ([]byte, error) {
+	u, err := url.Parse(filePath)
+	if err != nil {
+		return ioutil.ReadFile(filePath)
+	}
+
+	g, err := p.ByScheme(u.Scheme)
+	if err != nil {
+		return ioutil.ReadFile(filePath)
+	}
+	data, err := g.Get(filePath, getter.WithURL(filePath))
+	if err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
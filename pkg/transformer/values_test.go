@@ -0,0 +1,135 @@
+package transformer
+
+import (
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+const pemData = "-----BEGIN DATA-----\nkey=value,other=value==\n-----END DATA-----\n"
+
+func TestValuesMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		values Values
+		want   map[string]interface{}
+	}{
+		{
+			name:   "empty",
+			values: Values{},
+			want:   map[string]interface{}{},
+		},
+		{
+			name:   "later values files win",
+			values: Values{ValueFiles: []string{"testdata/values/base.yaml", "testdata/values/prod.yaml"}},
+			want: map[string]interface{}{
+				"controller": map[string]interface{}{"replicas": 2.0, "image": "mesh/controller:1.0.0", "debug": false},
+			},
+		},
+		{
+			name: "raw values win over values files",
+			values: Values{
+				ValueFiles: []string{"testdata/values/base.yaml"},
+				Raw:        map[string]interface{}{"controller": map[string]interface{}{"debug": true}},
+			},
+			want: map[string]interface{}{
+				"controller": map[string]interface{}{"replicas": 1.0, "image": "mesh/controller:1.0.0", "debug": true},
+			},
+		},
+		{
+			name: "set wins over raw values",
+			values: Values{
+				Raw: map[string]interface{}{"controller": map[string]interface{}{"replicas": 3}},
+				Set: []string{"controller.replicas=4", "controller.debug=true"},
+			},
+			want: map[string]interface{}{
+				"controller": map[string]interface{}{"replicas": int64(4), "debug": true},
+			},
+		},
+		{
+			name: "set-string wins over set",
+			values: Values{
+				Set:       []string{"controller.replicas=4"},
+				SetString: []string{"controller.replicas=5"},
+			},
+			want: map[string]interface{}{
+				"controller": map[string]interface{}{"replicas": "5"},
+			},
+		},
+		{
+			name: "set-file wins over set-string",
+			values: Values{
+				SetString: []string{"banner=none"},
+				SetFile:   []string{"banner=testdata/values/banner.txt"},
+			},
+			want: map[string]interface{}{
+				"banner": "line-1\nline-2\n",
+			},
+		},
+		{
+			name: "set-file keeps commas and equal signs",
+			values: Values{
+				SetFile: []string{"tls.crtPEM=testdata/values/data.pem,tls.keyPEM=testdata/values/banner.txt"},
+			},
+			want: map[string]interface{}{
+				"tls": map[string]interface{}{"crtPEM": pemData, "keyPEM": "line-1\nline-2\n"},
+			},
+		},
+		{
+			name: "set-file data wins over set-file",
+			values: Values{
+				SetFile: []string{"tls.crtPEM=testdata/values/banner.txt"},
+				SetFileData: map[string][]byte{
+					"tls.crtPEM":   []byte(pemData),
+					"tls.ca[0]":    []byte("ca=1,2"),
+					"tls.keyPEM":   []byte(""),
+					"global.trust": []byte(pemData),
+				},
+			},
+			want: map[string]interface{}{
+				"tls": map[string]interface{}{
+					"crtPEM": pemData,
+					"keyPEM": "",
+					"ca":     []interface{}{"ca=1,2"},
+				},
+				"global": map[string]interface{}{"trust": pemData},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.values.merge(getter.Providers{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merge returned %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValuesMergeKeepsRaw(t *testing.T) {
+	raw := map[string]interface{}{"controller": map[string]interface{}{"replicas": 3}}
+	values := Values{Raw: raw, Set: []string{"controller.replicas=4"}}
+
+	if _, err := values.merge(getter.Providers{}); err != nil {
+		t.Fatal(err)
+	}
+	if replicas := raw["controller"].(map[string]interface{})["replicas"]; replicas != 3 {
+		t.Errorf("merge changed the raw values to %v", replicas)
+	}
+}
+
+func TestValuesMergeMissingFile(t *testing.T) {
+	for _, values := range []Values{
+		{ValueFiles: []string{"testdata/values/missing.yaml"}},
+		{SetFile: []string{"banner=testdata/values/missing.txt"}},
+	} {
+		if _, err := values.merge(getter.Providers{}); err == nil {
+			t.Errorf("merge of %#v should fail", values)
+		}
+	}
+}
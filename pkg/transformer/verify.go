@@ -0,0 +1,262 @@
+package transformer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// The cosign signature of an OCI artifact is the simple signing layer of the
+// artifact tagged after the digest of the signed manifest
+const (
+	cosignLayerMediaType      = "application/vnd.dev.cosign.simplesigning.v1+json"
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+)
+
+// VerifyOptions are the options of verifying the integrity of the chart of a
+// request, nothing is verified when they are empty
+type VerifyOptions struct {
+	// Keyring is the path of the PGP keyring the provenance files are verified
+	// against, the chart.tgz.prov file next to the archive in the chart repo
+	// or next to a local chart archive
+	Keyring string
+	// CosignKey is the PEM public key the cosign signatures of the charts of an
+	// OCI registry are verified with
+	CosignKey []byte
+	// Required fails the transform of a chart which has no provenance file or
+	// signature, a chart directory or an in-memory chart. Without it only the
+	// charts which have one are verified. A provenance file or a signature
+	// which does not verify fails the transform in any case.
+	Required bool
+}
+
+// VerifyError is the failure of verifying the integrity of a chart
+type VerifyError struct {
+	Chart string
+	Err   error
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("verify the chart %s failed: %s", e.Chart, e.Err)
+}
+
+// Cause returns the underlying error of the failure
+func (e *VerifyError) Cause() error {
+	return e.Err
+}
+
+// unverified returns the error of a chart which has nothing to verify it with,
+// it is only logged unless the verification is required
+func unverified(req *Request, chart string, err error) error {
+	if req.Verify.Required {
+		return &VerifyError{Chart: chart, Err: err}
+	}
+	requestLogger(req).Warnf("The chart %s is not verified: %s", chart, err)
+	return nil
+}
+
+// verifyLocalChart verifies the local chart archive or the in-memory chart of
+// the request against its provenance file
+func verifyLocalChart(req *Request) error {
+	if req.Verify.Keyring == "" && !req.Verify.Required {
+		return nil
+	}
+	if req.Chart != nil {
+		return unverified(req, req.Chart.Name(), errors.New("an in-memory chart has no provenance file"))
+	}
+	if fi, err := os.Stat(req.ChartPath); err != nil || fi.IsDir() {
+		return unverified(req, req.ChartPath, errors.New("a chart directory has no provenance file"))
+	}
+
+	prov, err := ioutil.ReadFile(req.ChartPath + ".prov")
+	if err != nil {
+		return unverified(req, req.ChartPath, errors.Wrap(err, "no provenance file"))
+	}
+	return verifyProvenance(req, req.ChartPath, filepath.Base(req.ChartPath), prov)
+}
+
+// verifyRepoChart verifies the chart archive of a chart repo against the
+// provenance file next to the archive URL, it is cached next to the archive
+func verifyRepoChart(ctx context.Context, settings *cli.EnvSettings, req *Request, entry *repoEntry, chartURL, archive string) error {
+	if req.Verify.Keyring == "" && !req.Verify.Required {
+		return nil
+	}
+	name := filepath.Base(chartURL)
+
+	provPath := archive + ".prov"
+	prov, err := ioutil.ReadFile(provPath)
+	if err != nil {
+		data, err := downloadFile(ctx, settings, entry, repoOptions(req, entry), chartURL+".prov")
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return unverified(req, name, errors.Wrap(err, "no provenance file"))
+		}
+		prov = data.Bytes()
+	}
+
+	if err := verifyProvenance(req, archive, name, prov); err != nil {
+		return err
+	}
+	return writeFile(provPath, prov)
+}
+
+// verifyProvenance verifies the chart archive against the provenance file with
+// the keyring of the request, the provenance file refers to the archive by name
+func verifyProvenance(req *Request, archive, name string, prov []byte) error {
+	if req.Verify.Keyring == "" {
+		return &VerifyError{Chart: name, Err: errors.New("no keyring to verify the provenance file against")}
+	}
+	sig, err := provenance.NewFromKeyring(req.Verify.Keyring, "")
+	if err != nil {
+		return &VerifyError{Chart: name, Err: errors.Wrapf(err, "failed loading the keyring %s", req.Verify.Keyring)}
+	}
+
+	// The archives are cached by digest, they are verified under their name
+	dir, err := ioutil.TempDir("", "meshinfra-verify")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	data, err := ioutil.ReadFile(archive)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".prov"), prov, 0600); err != nil {
+		return err
+	}
+
+	ver, err := sig.Verify(filepath.Join(dir, name), filepath.Join(dir, name+".prov"))
+	if err != nil {
+		return &VerifyError{Chart: name, Err: err}
+	}
+	for identity := range ver.SignedBy.Identities {
+		requestLogger(req).Infof("The chart %s is signed by %s", name, identity)
+	}
+	return nil
+}
+
+// verifyCosign verifies the manifest with the digest against its cosign
+// signatures, one signature of the key of the request is enough
+func (c *registryClient) verifyCosign(ctx context.Context, digest string) error {
+	chart := c.ref.String()
+	if c.req.Verify.CosignKey == nil {
+		if c.req.Verify.Required {
+			return &VerifyError{Chart: chart, Err: errors.New("no cosign key to verify the signature with")}
+		}
+		return nil
+	}
+	key, err := parsePublicKey(c.req.Verify.CosignKey)
+	if err != nil {
+		return &VerifyError{Chart: chart, Err: err}
+	}
+
+	tag := "sha256-" + digest + ".sig"
+	data, err := c.get(ctx, "/v2/"+c.ref.repository+"/manifests/"+tag, ociManifestMediaType)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return unverified(c.req, chart, errors.Wrap(err, "no cosign signature"))
+	}
+	var manifest ociManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return &VerifyError{Chart: chart, Err: errors.Wrap(err, "failed parsing the signature manifest")}
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != cosignLayerMediaType {
+			continue
+		}
+		payload, err := c.get(ctx, "/v2/"+c.ref.repository+"/blobs/"+layer.Digest, "")
+		if err != nil {
+			return &VerifyError{Chart: chart, Err: errors.Wrap(err, "failed pulling the signature payload")}
+		}
+		if err := verifySignature(key, payload, layer.Digest, layer.Annotations[cosignSignatureAnnotation], digest); err != nil {
+			requestLogger(c.req).Debugf("Skip the cosign signature %s of %s: %s", layer.Digest, chart, err)
+			continue
+		}
+		requestLogger(c.req).Infof("The chart %s is signed with the cosign key", chart)
+		return nil
+	}
+	return &VerifyError{Chart: chart, Err: errors.New("no cosign signature verifies with the key")}
+}
+
+// verifySignature verifies the simple signing payload of a signature of the
+// manifest with the digest
+func verifySignature(key crypto.PublicKey, payload []byte, payloadDigest, signature, digest string) error {
+	sum := sha256.Sum256(payload)
+	if "sha256:"+hex.EncodeToString(sum[:]) != payloadDigest {
+		return errors.New("the payload does not match its digest")
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.Wrap(err, "invalid signature")
+	}
+
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		var rs struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(sig, &rs); err != nil {
+			return errors.Wrap(err, "invalid signature")
+		}
+		if !ecdsa.Verify(k, sum[:], rs.R, rs.S) {
+			return errors.New("the signature does not verify")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig); err != nil {
+			return errors.Wrap(err, "the signature does not verify")
+		}
+	default:
+		return errors.Errorf("unsupported key type %T", key)
+	}
+
+	var simple struct {
+		Critical struct {
+			Image struct {
+				Digest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &simple); err != nil {
+		return errors.Wrap(err, "invalid payload")
+	}
+	if simple.Critical.Image.Digest != "sha256:"+digest {
+		return errors.Errorf("the payload signs %s", simple.Critical.Image.Digest)
+	}
+	return nil
+}
+
+// parsePublicKey parses the PEM public key
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("the cosign key is no PEM public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cosign key")
+	}
+	return key, nil
+}
@@ -0,0 +1,195 @@
+package transformer_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Aisuko/meshinfra/pkg/transformer"
+	"github.com/Aisuko/meshinfra/pkg/transformer/registrytest"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+const (
+	keyring      = "testdata/keys/helm-test-key.pub"
+	otherKeyring = "testdata/keys/helm-password-key.secret"
+)
+
+// signChart writes the provenance file of the chart archive signed with the
+// test key
+func signChart(t *testing.T, archive string) {
+	sig, err := provenance.NewFromFiles("testdata/keys/helm-test-key.secret", keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prov, err := sig.ClearSign(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(archive+".prov", []byte(prov), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wantVerifyError fails the test unless the error is a VerifyError
+func wantVerifyError(t *testing.T, err error, wantErr bool) {
+	t.Helper()
+	if !wantErr {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if _, ok := err.(*transformer.VerifyError); !ok {
+		t.Fatalf("Transform returned %v, want a VerifyError", err)
+	}
+}
+
+func TestHelmTransformProvenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	charts := filepath.Join(dir, "charts")
+	srv := chartRepo(t, charts, "1.0.0", "1.1.0", "1.2.0")
+	defer srv.Close()
+
+	signChart(t, filepath.Join(charts, "mesh-1.0.0.tgz"))
+	// The provenance file of 1.1.0 does not sign its archive
+	prov, err := ioutil.ReadFile(filepath.Join(charts, "mesh-1.0.0.tgz.prov"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(charts, "mesh-1.1.0.tgz.prov"), prov, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		verify  transformer.VerifyOptions
+		wantErr bool
+	}{
+		{name: "signed", version: "1.0.0", verify: transformer.VerifyOptions{Keyring: keyring, Required: true}},
+		{name: "unknown signer", version: "1.0.0", verify: transformer.VerifyOptions{Keyring: otherKeyring}, wantErr: true},
+		{name: "tampered", version: "1.1.0", verify: transformer.VerifyOptions{Keyring: keyring}, wantErr: true},
+		{name: "unsigned", version: "1.2.0", verify: transformer.VerifyOptions{Keyring: keyring}},
+		{name: "unsigned required", version: "1.2.0", verify: transformer.VerifyOptions{Keyring: keyring, Required: true}, wantErr: true},
+		{name: "no keyring required", version: "1.0.0", verify: transformer.VerifyOptions{Required: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := repoRequest(srv.URL, transformer.Settings{
+				RepositoryConfig: filepath.Join(dir, "helm", "repositories.yaml"),
+				RepositoryCache:  filepath.Join(dir, "helm", "repository"),
+			})
+			req.Version = tt.version
+			req.Verify = tt.verify
+
+			_, err := (&transformer.Helm{}).Transform(context.Background(), req)
+			wantVerifyError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestHelmTransformProvenanceLocalChart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meshinfra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := chartutil.Save(ch, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signChart(t, archive)
+
+	tests := []struct {
+		name    string
+		req     *transformer.Request
+		wantErr bool
+	}{
+		{name: "archive", req: &transformer.Request{ChartPath: archive}},
+		{name: "directory", req: &transformer.Request{ChartPath: chartPath}},
+		{name: "directory required", req: &transformer.Request{ChartPath: chartPath, Verify: transformer.VerifyOptions{Required: true}}, wantErr: true},
+		{name: "in-memory required", req: &transformer.Request{Chart: ch, Verify: transformer.VerifyOptions{Required: true}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.ReleaseName = "mesh"
+			tt.req.Verify.Keyring = keyring
+
+			_, err := (&transformer.Helm{}).Transform(context.Background(), tt.req)
+			wantVerifyError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestHelmTransformCosign(t *testing.T) {
+	_, settings, cleanup := cacheTest(t)
+	defer cleanup()
+
+	reg := registrytest.New()
+	defer reg.Close()
+	pushCharts(t, reg, "1.0.0", "1.1.0")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Sign("meshes", "mesh", "1.0.0", key); err != nil {
+		t.Fatal(err)
+	}
+	publicKey := func(key *ecdsa.PrivateKey) []byte {
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		verify  transformer.VerifyOptions
+		wantErr bool
+	}{
+		{name: "signed", version: "1.0.0", verify: transformer.VerifyOptions{CosignKey: publicKey(key), Required: true}},
+		{name: "other key", version: "1.0.0", verify: transformer.VerifyOptions{CosignKey: publicKey(other)}, wantErr: true},
+		{name: "unsigned", version: "1.1.0", verify: transformer.VerifyOptions{CosignKey: publicKey(key)}},
+		{name: "unsigned required", version: "1.1.0", verify: transformer.VerifyOptions{CosignKey: publicKey(key), Required: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := ociRequest(reg.Address("meshes"), settings)
+			req.Version = tt.version
+			req.Verify = tt.verify
+
+			_, err := (&transformer.Helm{}).Transform(context.Background(), req)
+			wantVerifyError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package transformer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// versionMatcher matches the chart versions against the version of a request
+type versionMatcher struct {
+	constraint *semver.Constraints
+}
+
+// newVersionMatcher returns the matcher of the version of the request, an
+// empty version matches every stable version, or every version with devel.
+// Like helm, a constraint only matches a prerelease when it names a
+// prerelease itself, devel does not widen it.
+func newVersionMatcher(req *Request) (*versionMatcher, error) {
+	version := req.Version
+	if version == "" {
+		version = "*"
+		if req.Devel {
+			version = ">0.0.0-0"
+		}
+	}
+	c, err := semver.NewConstraint(version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid chart version %q", req.Version)
+	}
+	return &versionMatcher{constraint: c}, nil
+}
+
+// match reports whether the chart version satisfies the version
+func (m *versionMatcher) match(version string) bool {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return m.constraint.Check(v)
+}
+
+// resolveVersion returns the latest version of the chart of the request in the
+// repo index which matches the version of the request
+func resolveVersion(index *repo.IndexFile, req *Request) (*repo.ChartVersion, error) {
+	m, err := newVersionMatcher(req)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, ok := index.Entries[req.ChartName]
+	if !ok || len(versions) == 0 {
+		return nil, errors.Errorf("chart %q not found in the %s repo", req.ChartName, req.RepoName)
+	}
+
+	// The versions of the index are sorted from the latest on
+	available := make([]string, 0, len(versions))
+	for _, cv := range versions {
+		if m.match(cv.Version) {
+			return cv, nil
+		}
+		available = append(available, cv.Version)
+	}
+	return nil, errors.Errorf("no version of the chart %s in the %s repo matches %q (available: %s)",
+		req.ChartName, req.RepoName, versionOrLatest(req), strings.Join(available, ", "))
+}
+
+// ListVersions returns the versions of the chart of the request which match
+// its version, from the latest on, an empty version lists every stable
+// version. The versions are the ones of the chart repo or the registry, the
+// version of a local or an in-memory chart is the only one it has.
+func ListVersions(ctx context.Context, req *Request) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, done := withHTTPClients(ctx)
+	defer done()
+
+	m, err := newVersionMatcher(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var available []string
+	switch {
+	case req.Chart != nil || req.ChartPath != "":
+		ch := req.Chart
+		if ch == nil {
+			if ch, err = loader.Load(req.ChartPath); err != nil {
+				return nil, errors.Wrapf(err, "failed loading the chart %q", req.ChartPath)
+			}
+		}
+		available = []string{ch.Metadata.Version}
+	case IsOCI(req.ChartRepoAddress):
+		ref, err := parseOCIRef(req.ChartRepoAddress, req.ChartName)
+		if err != nil {
+			return nil, err
+		}
+		c, err := newRegistryClient(ctx, envSettings(req), req, ref)
+		if err != nil {
+			return nil, err
+		}
+		versions, err := c.versions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			available = append(available, v.Original())
+		}
+	default:
+		settings := envSettings(req)
+		if err := addRepo(ctx, settings, req); err != nil {
+			return nil, err
+		}
+		index, err := loadIndex(settings, req.ChartRepoAddress, req.Repo)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed loading the index of the %s repo", req.RepoName)
+		}
+		versions, ok := index.Entries[req.ChartName]
+		if !ok {
+			return nil, errors.Errorf("chart %q not found in the %s repo", req.ChartName, req.RepoName)
+		}
+		for _, cv := range versions {
+			available = append(available, cv.Version)
+		}
+	}
+
+	matched := []string{}
+	for _, version := range available {
+		if m.match(version) {
+			matched = append(matched, version)
+		}
+	}
+	return matched, nil
+}
+
+// checkVersion returns an error when the version of a local or an in-memory
+// chart does not match the version of the request
+func checkVersion(ch *chart.Chart, req *Request) error {
+	if req.Version == "" && !req.Devel {
+		return nil
+	}
+	m, err := newVersionMatcher(req)
+	if err != nil {
+		return err
+	}
+	if !m.match(ch.Metadata.Version) {
+		return errors.Errorf("the version %s of the chart %s does not match %q", ch.Metadata.Version, ch.Name(), versionOrLatest(req))
+	}
+	return nil
+}
+
+func versionOrLatest(req *Request) string {
+	if req.Version == "" {
+		return "latest"
+	}
+	return req.Version
+}
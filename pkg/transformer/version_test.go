@@ -0,0 +1,41 @@
+package transformer
+
+import "testing"
+
+func TestVersionMatcher(t *testing.T) {
+	tests := []struct {
+		version string
+		devel   bool
+		chart   string
+		want    bool
+	}{
+		{"", false, "1.2.3", true},
+		{"", false, "1.2.3-rc.1", false},
+		{"", true, "1.2.3-rc.1", true},
+		{"1.2.3", false, "1.2.3", true},
+		{"1.2.3", false, "1.2.4", false},
+		{"~1.2", false, "1.2.9", true},
+		{"~1.2", false, "1.3.0", false},
+		{"~1.2", false, "1.2.9-rc.1", false},
+		{"~1.2", true, "1.2.9-rc.1", false},
+		{"~1.2-0", false, "1.2.9-rc.1", true},
+		{"~1.2-0", false, "1.3.0-rc.1", false},
+		{">=1.2.0", true, "1.2.0-rc.1", false},
+		{">=1.2.0", true, "1.2.0", true},
+		{"^2.0.0", true, "2.0.0-beta.1", false},
+		{"^2.0.0-0", false, "2.0.0-beta.1", true},
+		{"1.2.3", true, "1.2.3-rc.1", false},
+		{"1.2.3-rc.1", false, "1.2.3-rc.1", true},
+		{">=1.0.0", false, "not-a-version", false},
+	}
+
+	for _, tt := range tests {
+		m, err := newVersionMatcher(&Request{Version: tt.version, Devel: tt.devel})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.match(tt.chart); got != tt.want {
+			t.Errorf("%q (devel %v) matches %s: %v, want %v", tt.version, tt.devel, tt.chart, got, tt.want)
+		}
+	}
+}